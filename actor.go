@@ -3,23 +3,31 @@
 package goactors
 
 import (
+	"fmt"
 	"sort"
+	"time"
 )
 
 type actorProxy struct {
 	proxiedActor     ActorRef
+	proxyRef         ActorRef
 	messageChannel   chan actorMessage
 	bufferedMessages []actorMessage
 	stopChannel      chan struct{}
+	doneChannel      chan struct{}
 }
 
 type actorImpl struct {
-	messageChannel chan actorMessage
-	path           string
-	messageBuffer  []interface{}
-	actorImpl      Actor
-	context        actorContextImpl
-	proxy          *actorProxy
+	messageChannel  chan actorMessage
+	killChannel     chan poisonPillMessage
+	status          *mailboxStatus
+	path            string
+	ref             ActorRef
+	pendingMessages []actorMessage
+	actorImpl       Actor
+	factoryFunction func() Actor
+	context         actorContextImpl
+	proxy           *actorProxy
 }
 
 type Actor interface {
@@ -28,15 +36,35 @@ type Actor interface {
 	Receive(ctxt ActorContext, message interface{})
 }
 
-func (impl *actorImpl) stop(stopChannel chan<- bool) {
+// PreRestarter can be implemented by an actor to clean up before it is replaced by a
+// restart. It is called instead of OnStop, after the children were stopped. The last
+// message is the one that failed, or nil if the actor failed while starting.
+type PreRestarter interface {
+	PreRestart(context ActorContext, reason interface{}, lastMessage interface{})
+}
+
+// PostRestarter can be implemented by an actor to initialize differently after a restart.
+// It is called on the new instance instead of OnStart.
+type PostRestarter interface {
+	PostRestart(context ActorContext, reason interface{})
+}
+
+// stop stops the actor after its children were stopped
+func (impl *actorImpl) stop(stopChannel chan<- bool, reason StopReason) {
+	self := impl.context.self
 	impl.context.self = nil // self is destructed at this point
-	impl.actorImpl.OnStop()
+	// Nobody supervises an actor that is already stopping, so the parent and the
+	// watchers learn about a failure from the reason
+	reason.StopFailure = impl.stopBehavior(reason)
+	impl.context.timers.CancelAll()
 
-	// Have the control thread unregister the actor
+	// Have the control thread unregister the actor and notify its watchers
 	stopResponseChannel := make(chan interface{})
 	defer close(stopResponseChannel)
 	impl.context.systemControlChannel <- actorStopRequest{
 		path:            impl.path,
+		ref:             self,
+		reason:          reason,
 		responseChannel: stopResponseChannel,
 	}
 	<-stopResponseChannel
@@ -46,16 +74,77 @@ func (impl *actorImpl) stop(stopChannel chan<- bool) {
 		stopChannel <- true
 	}
 
-	// Kill the proxy go routine if a proxy is being used
+	// Kill the proxy go routine if a proxy is being used, it hands its buffered messages
+	// to the dead letters on the way out
 	if impl.proxy != nil {
 		close(impl.proxy.stopChannel)
+		<-impl.proxy.doneChannel
 		impl.proxy = nil
 	}
+
+	// Release senders blocked on a full mailbox, then wait for the ones still sending
+	close(impl.status.closing)
+	impl.status.lock.Lock()
+	impl.status.closed = true
+	impl.status.lock.Unlock()
+
+	impl.drainMailbox(self)
+
+	// Closed after the result, so that a parent waiting on either channel always sees the
+	// result first
+	close(impl.status.stopped)
+}
+
+func (impl *actorImpl) stopBehavior(reason StopReason) (failure interface{}) {
+	defer func() {
+		failure = recover()
+	}()
+
+	if observer, ok := impl.actorImpl.(StopObserver); ok {
+		observer.OnStopped(&impl.context, reason)
+	} else {
+		impl.actorImpl.OnStop()
+	}
+	return nil
+}
+
+// drainMailbox hands every message the stopped actor did not process to the dead letters
+func (impl *actorImpl) drainMailbox(self ActorRef) {
+	for _, actorMsg := range impl.context.takeStash() {
+		impl.deadLetter(self, actorMsg)
+	}
+
+	for _, actorMsg := range impl.pendingMessages {
+		impl.deadLetter(self, actorMsg)
+	}
+	impl.pendingMessages = nil
+
+	for {
+		select {
+		case actorMsg := <-impl.messageChannel:
+			impl.deadLetter(self, actorMsg)
+		default:
+			return
+		}
+	}
+}
+
+func (impl *actorImpl) deadLetter(self ActorRef, actorMsg actorMessage) {
+	impl.context.deadLetters.undeliverable(DeadLetter{Sender: actorMsg.sender, Recipient: self, Message: actorMsg.message})
+}
+
+// failAsker fails the future or stream that asked for the message the actor panicked on,
+// since no reply is coming
+func (impl *actorImpl) failAsker(actorMsg actorMessage, reason interface{}) {
+	if asker, ok := actorMsg.sender.(asker); ok {
+		asker.failAsk(fmt.Errorf("goactors: %s failed before replying: %v", impl.context.path, reason))
+	}
 }
 
 const (
 	actorMessageResultStop    = iota
 	actorMessageResultTryNext = iota
+	actorMessageResultHandled = iota
 )
 
 func (impl *actorImpl) tryProcessSystemMessage(message actorMessage) int {
@@ -63,97 +152,410 @@ func (impl *actorImpl) tryProcessSystemMessage(message actorMessage) int {
 	case poisonPillMessage:
 		// fmt.Printf("Received poison pill %v\n", impl.context.path)
 		pill := message.message.(poisonPillMessage)
-		childrenResultChannel := make(chan bool)
-		defer close(childrenResultChannel)
+		impl.stopChildren(impl.cascadeReason(pill.reason), pill.immediate)
+		impl.stop(pill.resultChannel, pill.reason)
+		return actorMessageResultStop
+	case actorFailedMessage:
+		failure := message.message.(actorFailedMessage)
+		return impl.superviseChild(message.sender, failure.reason)
+	case childStoppedMessage:
+		stopped := message.message.(childStoppedMessage)
+		impl.childStopped(stopped.ref, stopped.reason)
+		return actorMessageResultHandled
+	case Terminated:
+		// Drop notifications for actors that were unwatched in the meantime
+		if !impl.context.stopWatching(message.message.(Terminated).Ref) {
+			return actorMessageResultHandled
+		}
+		return actorMessageResultTryNext
+	case supervisorDirectiveMessage:
+		// Running actors only get a directive when a failed sibling restarts all children
+		if directive := message.message.(supervisorDirectiveMessage); directive.directive == Restart {
+			if failure := impl.restart(directive.reason, nil); failure != nil && impl.fail(failure, nil) == Stop {
+				return actorMessageResultStop
+			}
+		}
+		return actorMessageResultHandled
+	default:
+		return actorMessageResultTryNext
+	}
+
+}
+
+// stopChildren stops every child in name order and waits for each one to finish.
+// Children stopped immediately don't process the messages left in their mailbox.
+func (impl *actorImpl) stopChildren(reason StopReason, immediate bool) {
+	children := impl.context.takeChildren()
+
+	// Sort the children so we have consistent stopping
+	sortedChildren := make([]string, 0, len(children))
+	for k := range children {
+		sortedChildren = append(sortedChildren, k)
+	}
+
+	sort.Strings(sortedChildren)
+	for _, val := range sortedChildren {
+		impl.stopChild(children[val], reason, immediate)
+	}
+}
+
+func (impl *actorImpl) stopChild(child ActorRef, reason StopReason, immediate bool) {
+	childrenResultChannel := make(chan bool)
+	defer close(childrenResultChannel)
+
+	pill := poisonPillMessage{resultChannel: childrenResultChannel, reason: reason, immediate: immediate}
+	if immediate {
+		kill(child, impl.context.SelfRef(), pill)
+	} else {
+		child.Send(impl.context.SelfRef(), pill)
+	}
+
+	// The child may have been stopped by someone else, in which case the pill is never processed
+	select {
+	case <-childrenResultChannel:
+	case <-stoppedChannel(child):
+	}
+}
+
+// childStopped forgets a child that stopped on its own and tells the behavior about it
+func (impl *actorImpl) childStopped(child ActorRef, reason StopReason) {
+	impl.context.removeChild(child)
 
-		// Sort the children so we have consistent stopping
-		sortedChildren := make([]string, 0, len(impl.context.children))
-		for k := range impl.context.children {
-			sortedChildren = append(sortedChildren, k)
+	if observer, ok := impl.actorImpl.(ChildStopObserver); ok {
+		observer.OnChildStopped(&impl.context, child, reason)
+	}
+}
+
+// superviseChild applies the parent's supervisor strategy to a failed child
+func (impl *actorImpl) superviseChild(child ActorRef, reason interface{}) int {
+	// The child may have been stopped before its failure was processed. The registry
+	// is checked rather than the children map, since the root context can create
+	// children from other goroutines.
+	if impl.context.FindActor(child.Path()) == nil {
+		return actorMessageResultHandled
+	}
+
+	// The default strategy counts restarts, so every parent gets its own
+	if impl.context.supervisorStrategy == nil {
+		impl.context.supervisorStrategy = DefaultSupervisorStrategy()
+	}
+	strategy := impl.context.supervisorStrategy
+
+	allChildren := strategy.AppliesToAllChildren()
+
+	switch strategy.Decide(child, reason) {
+	case Resume:
+		child.Send(impl.context.self, supervisorDirectiveMessage{directive: Resume})
+	case Restart:
+		child.Send(impl.context.self, supervisorDirectiveMessage{directive: Restart})
+		if allChildren {
+			for _, sibling := range impl.context.childRefs() {
+				if sibling.Path() != child.Path() {
+					sibling.Send(impl.context.self, supervisorDirectiveMessage{directive: Restart, reason: reason})
+				}
+			}
+		}
+	case Stop:
+		stopReason := StopReason{Cause: StopFailed, Failure: reason}
+		if allChildren {
+			impl.stopChildren(stopReason, false)
+			break
 		}
 
-		sort.Strings(sortedChildren)
-		for _, val := range sortedChildren {
-			impl.context.children[val].Send(
-				impl.context.SelfRef(),
-				poisonPillMessage{resultChannel: childrenResultChannel})
-			<-childrenResultChannel
+		impl.stopChild(child, stopReason, false)
+		impl.context.removeChild(child)
+	case Escalate:
+		switch impl.fail(reason, nil) {
+		case Stop:
+			return actorMessageResultStop
+		case Resume:
+			// The child is still waiting for a decision
+			child.Send(impl.context.self, supervisorDirectiveMessage{directive: Resume})
 		}
+		// A restart already stopped the child along with its siblings
+	}
+	return actorMessageResultHandled
+}
 
-		impl.stop(pill.resultChannel)
-		return actorMessageResultStop
+// fail reports a failure to the parent and suspends the actor until the parent
+// decides how to continue. It returns the directive that ended the suspension,
+// which is Stop if the actor was stopped in the meantime.
+func (impl *actorImpl) fail(reason interface{}, lastMessage interface{}) Directive {
+	for {
+		if impl.context.parent == nil {
+			// The root actor has nobody to escalate to, so the system is stopped
+			impl.stopChildren(StopReason{Cause: StopSystemShutdown}, false)
+			impl.stop(nil, StopReason{Cause: StopFailed, Failure: reason})
+			return Stop
+		}
+
+		impl.context.parent.Send(impl.context.self, actorFailedMessage{reason: reason})
+
+		switch directive := impl.awaitDirective(); directive {
+		case Restart:
+			if reason = impl.restart(reason, lastMessage); reason == nil {
+				return Restart
+			}
+			// The new instance failed to start, so the parent has to decide again
+			lastMessage = nil
+		default:
+			return directive
+		}
+	}
+}
+
+// awaitDirective waits for the parent's decision, holding back user messages
+// until the actor is able to process them again
+func (impl *actorImpl) awaitDirective() Directive {
+	for {
+		var actorMsg actorMessage
+		select {
+		case pill := <-impl.killChannel:
+			actorMsg = actorMessage{message: pill}
+		case actorMsg = <-impl.messageChannel:
+		}
+
+		switch actorMsg.message.(type) {
+		case supervisorDirectiveMessage:
+			return actorMsg.message.(supervisorDirectiveMessage).directive
+		case poisonPillMessage:
+			impl.tryProcessSystemMessage(actorMsg)
+			return Stop
+		default:
+			impl.pendingMessages = append(impl.pendingMessages, actorMsg)
+		}
+	}
+}
+
+// restart stops the children and the current behavior, then starts a new
+// behavior from the factory function. It returns the failure if stopping the old
+// behavior, creating the new one or starting it panicked. The old behavior stays in
+// place until a new one was created.
+func (impl *actorImpl) restart(reason interface{}, lastMessage interface{}) interface{} {
+	impl.stopChildren(StopReason{Cause: StopParentRestarted}, false)
+	if failure := impl.preRestart(reason, lastMessage); failure != nil {
+		return failure
+	}
+
+	behavior, failure := impl.newBehavior()
+	if failure != nil {
+		return failure
+	}
+	impl.actorImpl = behavior
+	impl.context.incarnation++
+	impl.context.behaviors = nil
+	impl.context.receiveTimeout = 0
+	impl.context.timers.CancelAll()
+
+	// The new instance gets the stashed messages again
+	impl.context.unstashed = impl.context.takeStash()
+
+	if restarter, ok := behavior.(PostRestarter); ok {
+		return impl.postRestart(restarter, reason)
+	}
+	return impl.start()
+}
+
+func (impl *actorImpl) preRestart(reason interface{}, lastMessage interface{}) (failure interface{}) {
+	defer func() {
+		failure = recover()
+	}()
+
+	if restarter, ok := impl.actorImpl.(PreRestarter); ok {
+		restarter.PreRestart(&impl.context, reason, lastMessage)
+	} else {
+		impl.actorImpl.OnStop()
+	}
+	return nil
+}
+
+func (impl *actorImpl) newBehavior() (behavior Actor, failure interface{}) {
+	defer func() {
+		if failure = recover(); failure != nil {
+			behavior = nil
+		}
+	}()
+
+	if behavior = impl.factoryFunction(); behavior == nil {
+		return nil, "Could not create actor behavior implementation"
+	}
+	return behavior, nil
+}
+
+func (impl *actorImpl) postRestart(restarter PostRestarter, reason interface{}) (failure interface{}) {
+	defer func() {
+		failure = recover()
+	}()
+
+	restarter.PostRestart(&impl.context, reason)
+	return nil
+}
+
+func (impl *actorImpl) start() (failure interface{}) {
+	defer func() {
+		failure = recover()
+	}()
+
+	impl.actorImpl.OnStart(&impl.context)
+	return nil
+}
+
+func (impl *actorImpl) receive(message interface{}) (failure interface{}) {
+	defer func() {
+		failure = recover()
+	}()
+
+	switch message.(type) {
+	case pipedResultMessage:
+		piped := message.(pipedResultMessage)
+		message = piped.mapFn(piped.value, piped.err)
+		impl.context.message = message
+	case timerMessage:
+		timer, ok := impl.context.timers.accept(message.(timerMessage))
+		if !ok {
+			return nil
+		}
+		message = timer
+		impl.context.message = message
+	case continuationMessage:
+		// Continuations belong to the behavior that awaited, a restart drops them
+		continuation := message.(continuationMessage)
+		if continuation.incarnation == impl.context.incarnation {
+			continuation.fn(&impl.context, continuation.value, continuation.err)
+		}
+		return nil
+	}
+
+	if behavior := impl.context.currentBehavior(); behavior != nil {
+		behavior(&impl.context, message)
+		return nil
+	}
+
+	impl.actorImpl.Receive(&impl.context, message)
+	return nil
+}
+
+func (impl *actorImpl) nextMessage() actorMessage {
+	// A kill jumps the queue, including the messages held back during a failure
+	select {
+	case pill := <-impl.killChannel:
+		return actorMessage{message: pill}
 	default:
-		return actorMessageResultTryNext
 	}
 
+	if actorMsg, ok := impl.context.nextUnstashed(); ok {
+		return actorMsg
+	}
+
+	if len(impl.pendingMessages) > 0 {
+		actorMsg := impl.pendingMessages[0]
+		impl.pendingMessages = impl.pendingMessages[1:]
+		return actorMsg
+	}
+
+	// Waiting for longer than the receive timeout ends with a ReceiveTimeout message
+	var timeout <-chan time.Time
+	if impl.context.receiveTimeout > 0 {
+		timer := time.NewTimer(impl.context.receiveTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case pill := <-impl.killChannel:
+		return actorMessage{message: pill}
+	case actorMsg := <-impl.messageChannel:
+		return actorMsg
+	case <-timeout:
+		return actorMessage{message: ReceiveTimeout{}}
+	}
 }
 
-func (impl *actorImpl) runProxy() {
+func (impl *actorImpl) runProxy(proxy *actorProxy) {
 	// Proxy actors don't have an underlying implementation. They don't have a start/stop
 	// The don't deal with system messages. They literally forward everything to the
 	// underlying actor's message channel.
 	// ptrToContext := &impl.context
 	// fmt.Printf("Proxy actor %s is now receiving messages\n", ptrToContext.path)
 
-	if impl.proxy == nil {
+	// The proxy is passed in, since the actor drops its pointer to it when it stops
+	if proxy == nil {
 		panic("This is not a proxy actor")
 	}
 
 loop:
 	for {
-		if len(impl.proxy.bufferedMessages) > 0 {
-			msg := impl.proxy.bufferedMessages[0]
+		if len(proxy.bufferedMessages) > 0 {
+			msg := proxy.bufferedMessages[0]
 			select {
-			case val := <-impl.proxy.messageChannel:
-				impl.proxy.bufferedMessages = append(impl.proxy.bufferedMessages, val)
+			case val := <-proxy.messageChannel:
+				proxy.bufferedMessages = append(proxy.bufferedMessages, val)
 				break
 			case impl.messageChannel <- msg:
-				impl.proxy.bufferedMessages = impl.proxy.bufferedMessages[1:]
+				proxy.bufferedMessages = proxy.bufferedMessages[1:]
 				break
-			case <-impl.proxy.stopChannel:
+			case <-proxy.stopChannel:
 				break loop
 			}
 		} else {
 			select {
-			case val := <-impl.proxy.messageChannel:
-				impl.proxy.bufferedMessages = append(impl.proxy.bufferedMessages, val)
+			case val := <-proxy.messageChannel:
+				proxy.bufferedMessages = append(proxy.bufferedMessages, val)
 				break
-			case <-impl.proxy.stopChannel:
+			case <-proxy.stopChannel:
 				break loop
 			}
 		}
 	}
 
+	for _, actorMsg := range proxy.bufferedMessages {
+		impl.deadLetter(proxy.proxyRef, actorMsg)
+	}
+	proxy.bufferedMessages = nil
+	close(proxy.doneChannel)
+
 	// fmt.Printf("Proxy actor %s is stopping\n", ptrToContext.path)
 }
 
 func (impl *actorImpl) run(responseChannel chan<- ActorRef) {
 	ptrToContext := &impl.context
 
-	impl.actorImpl.OnStart(ptrToContext)
+	failure := impl.start()
 
 	// Once the actor is started, notify the creator
 	if responseChannel != nil {
 		responseChannel <- impl.context.self
 	}
 
+	if failure != nil && impl.fail(failure, nil) == Stop {
+		return
+	}
+
 	// fmt.Printf("Actor %s is now receiving messages\n", ptrToContext.path)
 
 loop:
-	for actorMsg := range impl.messageChannel {
+	for {
+		actorMsg := impl.nextMessage()
 		ptrToContext.sender = actorMsg.sender
+		ptrToContext.message = actorMsg.message
 
 		if systemProcessResult := impl.tryProcessSystemMessage(actorMsg); systemProcessResult == actorMessageResultStop {
 			// the actor system is shut down at this point, so just kill the loop
 			break loop
 		} else if systemProcessResult == actorMessageResultTryNext {
-			impl.actorImpl.Receive(ptrToContext, actorMsg.message)
+			if failure := impl.receive(actorMsg.message); failure != nil {
+				impl.failAsker(actorMsg, failure)
+				if impl.fail(failure, actorMsg.message) == Stop {
+					break loop
+				}
+			}
 		}
 		ptrToContext.sender = nil
+		ptrToContext.message = nil
 	}
 }
 
-func newActor(name string, controlChannel chan<- interface{}, request actorCreateRequest) *actorImpl {
+func newActor(name string, controlChannel chan<- interface{}, deadLetters *DeadLetters, scheduler *Scheduler, request actorCreateRequest) *actorImpl {
 	// Running in the context of the main system goroutine
 	behavior := request.factoryFunction()
 	if behavior == nil {
@@ -162,35 +564,42 @@ func newActor(name string, controlChannel chan<- interface{}, request actorCreat
 
 	var impl = new(actorImpl)
 	*impl = actorImpl{
-		path:           name,
-		messageChannel: make(chan actorMessage, 10),
-		actorImpl:      behavior,
+		path:            name,
+		messageChannel:  make(chan actorMessage, 10),
+		killChannel:     make(chan poisonPillMessage, 1),
+		status:          newMailboxStatus(),
+		actorImpl:       behavior,
+		factoryFunction: request.factoryFunction,
 
 		// Memory is owned by go thread below
 		context: actorContextImpl{
 			parent:               request.parent,
 			path:                 name,
 			children:             make(map[string]ActorRef),
+			watching:             make(map[string]ActorRef),
 			self:                 nil,
 			sender:               nil,
 			systemControlChannel: controlChannel,
+			deadLetters:          deadLetters,
+			timers:               newTimers(scheduler),
 		},
 	}
 
 	var ref = new(actorRef)
 	ref.name = name
 	ref.messageChannel = impl.messageChannel
+	ref.killChannel = impl.killChannel
+	ref.status = impl.status
+	ref.deadLetters = deadLetters
 	impl.context.self = ref
 
-	// Owned by the new actor
-	go impl.run(request.responseChannel)
-
 	// Create and wire up the proxy if requested
 	if request.proxy {
 		// Create the proxy actor to store message queue and pointer to original actor
 		impl.proxy = new(actorProxy)
 		impl.proxy.messageChannel = make(chan actorMessage)
 		impl.proxy.stopChannel = make(chan struct{})
+		impl.proxy.doneChannel = make(chan struct{})
 		impl.proxy.bufferedMessages = make([]actorMessage, 0, 10)
 		impl.proxy.proxiedActor = ref
 
@@ -199,10 +608,22 @@ func newActor(name string, controlChannel chan<- interface{}, request actorCreat
 		ref := new(actorRef)
 		ref.name = name
 		ref.messageChannel = impl.proxy.messageChannel
+		ref.killChannel = impl.killChannel
+		ref.status = impl.status
+		ref.deadLetters = deadLetters
+		ref.unbounded = true
+		impl.proxy.proxyRef = ref
 		impl.context.self = ref
 
-		go impl.runProxy()
+		go impl.runProxy(impl.proxy)
 	}
 
+	// Kept for lookups, since the actor clears its context's self when it stops
+	impl.ref = impl.context.self
+	impl.context.timers.self = impl.ref
+
+	// Owned by the new actor
+	go impl.run(request.responseChannel)
+
 	return impl
 }
@@ -0,0 +1,49 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ReplyTypeError is returned by Ask when the reply is not of the expected type
+type ReplyTypeError struct {
+	Expected reflect.Type
+	Reply    interface{}
+}
+
+func (err *ReplyTypeError) Error() string {
+	return fmt.Sprintf("goactors: expected a reply of type %v, received %T (%v)", err.Expected, err.Reply, err.Reply)
+}
+
+// Ask sends the request and waits up to the timeout for a reply of type Resp. It fails
+// with ErrAskTimeout, the failure of the ask, or a *ReplyTypeError.
+func Ask[Req any, Resp any](ref ActorRef, request Req, timeout time.Duration) (Resp, error) {
+	var response Resp
+	reply, err := ref.AskWithTimeout(request, timeout).Result()
+	if err != nil {
+		return response, err
+	}
+
+	expected := reflect.TypeOf((*Resp)(nil)).Elem()
+	if reply == nil && isNillable(expected) {
+		return response, nil
+	}
+
+	response, ok := reply.(Resp)
+	if !ok {
+		return response, &ReplyTypeError{Expected: expected, Reply: reply}
+	}
+	return response, nil
+}
+
+func isNillable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		return true
+	default:
+		return false
+	}
+}
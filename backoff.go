@@ -0,0 +1,173 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// DefaultBackoffBufferSize is how many messages a backoff supervisor buffers while its
+// child is down, unless BackoffOptions.MaxBuffered says otherwise
+const DefaultBackoffBufferSize = 1000
+
+// BackoffOptions configures how a backoff supervisor restarts its child
+type BackoffOptions struct {
+	// Delay before the first restart, doubled for every consecutive failure
+	MinBackoff time.Duration
+
+	// Upper bound for the delay. A child that stays up for longer than this resets the
+	// delay back to MinBackoff.
+	MaxBackoff time.Duration
+
+	// Adds up to RandomFactor times the delay as random jitter, so that children that
+	// failed together don't restart together
+	RandomFactor float64
+
+	// Hands messages sent while the child is down to the dead letters instead of
+	// buffering them until the child is started again
+	DropWhileDown bool
+
+	// How many messages are buffered while the child is down. Further messages go to
+	// the dead letters. Zero means DefaultBackoffBufferSize, negative means no limit.
+	MaxBuffered int
+}
+
+func (options BackoffOptions) validate() error {
+	switch {
+	case options.MinBackoff <= 0:
+		return errors.New("goactors: MinBackoff must be positive")
+	case options.MaxBackoff < options.MinBackoff:
+		return errors.New("goactors: MaxBackoff must not be less than MinBackoff")
+	case options.RandomFactor < 0:
+		return errors.New("goactors: RandomFactor must not be negative")
+	}
+	return nil
+}
+
+type backoffSupervisor struct {
+	options      BackoffOptions
+	childFactory func() Actor
+	childName    string
+	self         ActorRef
+	child        ActorRef
+	restarts     int
+	startedAt    time.Time
+	timers       *Timers
+	buffer       []actorMessage
+}
+
+type backoffRestartMessage struct{}
+
+// backoffTimerKey is the key of the restart among the supervisor's timers
+type backoffTimerKey struct{}
+
+// NewBackoffSupervisor returns an actor factory for use with CreateActorFromFunc. The
+// created actor runs the child as childName and forwards all messages to it. When the
+// child fails it is stopped and started again after an exponentially growing delay.
+// It fails if the options are invalid.
+func NewBackoffSupervisor(childFactory func() Actor, childName string, options BackoffOptions) (func() Actor, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	return func() Actor {
+		return &backoffSupervisor{
+			options:      options,
+			childFactory: childFactory,
+			childName:    childName,
+		}
+	}, nil
+}
+
+func (supervisor *backoffSupervisor) OnStart(context ActorContext) {
+	supervisor.self = context.SelfRef()
+	supervisor.timers = context.Timers()
+	context.SetSupervisorStrategy(supervisor)
+	supervisor.startChild(context)
+}
+
+func (supervisor *backoffSupervisor) OnStop() {
+	// Nothing to clean up, the actor system cancels a pending restart timer
+}
+
+func (supervisor *backoffSupervisor) Receive(context ActorContext, message interface{}) {
+	if _, ok := message.(backoffRestartMessage); ok {
+		supervisor.startChild(context)
+		return
+	}
+
+	if supervisor.child != nil {
+		supervisor.child.Send(context.SenderRef(), message)
+	} else if supervisor.options.DropWhileDown || supervisor.bufferFull() {
+		context.DeadLetters().undeliverable(DeadLetter{Sender: context.SenderRef(), Recipient: supervisor.self, Message: message})
+	} else {
+		supervisor.buffer = append(supervisor.buffer, actorMessage{sender: context.SenderRef(), message: message})
+	}
+}
+
+func (supervisor *backoffSupervisor) bufferFull() bool {
+	limit := supervisor.options.MaxBuffered
+	if limit == 0 {
+		limit = DefaultBackoffBufferSize
+	}
+	return limit > 0 && len(supervisor.buffer) >= limit
+}
+
+func (supervisor *backoffSupervisor) startChild(context ActorContext) {
+	child := context.CreateActorFromFunc(supervisor.childFactory, supervisor.childName)
+	if child == nil {
+		// The failed child may still hold the name while it stops, so try again later
+		supervisor.scheduleRestart(supervisor.options.MinBackoff)
+		return
+	}
+
+	supervisor.child = child
+	supervisor.startedAt = time.Now()
+
+	for _, msg := range supervisor.buffer {
+		supervisor.child.Send(msg.sender, msg.message)
+	}
+	supervisor.buffer = nil
+}
+
+// Decide implements SupervisorStrategy. The failed child is stopped right away and a
+// restart is scheduled once the backoff delay has passed.
+func (supervisor *backoffSupervisor) Decide(child ActorRef, reason interface{}) Directive {
+	if supervisor.child == nil || child.Path() != supervisor.child.Path() {
+		return Stop
+	}
+
+	if time.Since(supervisor.startedAt) > supervisor.options.MaxBackoff {
+		supervisor.restarts = 0
+	}
+
+	delay := supervisor.backoff()
+	supervisor.restarts++
+	supervisor.child = nil
+	supervisor.scheduleRestart(delay)
+	return Stop
+}
+
+func (supervisor *backoffSupervisor) scheduleRestart(delay time.Duration) {
+	supervisor.timers.StartSingle(backoffTimerKey{}, backoffRestartMessage{}, delay)
+}
+
+func (supervisor *backoffSupervisor) AppliesToAllChildren() bool {
+	return false
+}
+
+func (supervisor *backoffSupervisor) backoff() time.Duration {
+	delay := supervisor.options.MinBackoff
+	for i := 0; i < supervisor.restarts && delay < supervisor.options.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > supervisor.options.MaxBackoff {
+		delay = supervisor.options.MaxBackoff
+	}
+
+	jitter := time.Duration(rand.Float64() * supervisor.options.RandomFactor * float64(delay))
+	return delay + jitter
+}
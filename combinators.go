@@ -0,0 +1,136 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoFutures is the failure of FirstCompleted when it is given no futures
+var ErrNoFutures = errors.New("goactors: no futures to wait for")
+
+// The combinators never block. The functions passed to them run on the goroutine that
+// completes the future, usually the replying actor's, so they should not block either.
+
+func (future *futureImpl) derive() *futureImpl {
+	// The source future already carries the ask timeout
+	return newFuture(future.deadLetters, 0)
+}
+
+// callSafely runs fn and turns a panic into a failure of the derived future
+func callSafely(derived *futureImpl, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			derived.complete(futureResult{err: fmt.Errorf("goactors: future callback panicked: %v", r)})
+		}
+	}()
+	fn()
+}
+
+func (future *futureImpl) Map(fn func(value interface{}) interface{}) Future {
+	derived := future.derive()
+	future.onComplete(func(result futureResult) {
+		if result.err != nil {
+			derived.complete(result)
+			return
+		}
+
+		callSafely(derived, func() {
+			derived.complete(futureResult{value: fn(result.value)})
+		})
+	})
+	return derived
+}
+
+func (future *futureImpl) FlatMap(fn func(value interface{}) Future) Future {
+	derived := future.derive()
+	future.onComplete(func(result futureResult) {
+		if result.err != nil {
+			derived.complete(result)
+			return
+		}
+
+		callSafely(derived, func() {
+			next := fn(result.value)
+			if next == nil {
+				derived.complete(futureResult{})
+				return
+			}
+			next.onComplete(func(result futureResult) { derived.complete(result) })
+		})
+	})
+	return derived
+}
+
+func (future *futureImpl) Then(fn func(value interface{}) Future) Future {
+	return future.FlatMap(fn)
+}
+
+func (future *futureImpl) Recover(fn func(err error) interface{}) Future {
+	derived := future.derive()
+	future.onComplete(func(result futureResult) {
+		if result.err == nil {
+			derived.complete(result)
+			return
+		}
+
+		callSafely(derived, func() {
+			derived.complete(futureResult{value: fn(result.err)})
+		})
+	})
+	return derived
+}
+
+// Sequence returns a future of all the results as []interface{}, in the order of the
+// futures. It fails with the first failure among them.
+func Sequence(futures []Future) Future {
+	derived := newFuture(nil, 0)
+	values := make([]interface{}, len(futures))
+	if len(futures) == 0 {
+		derived.complete(futureResult{value: values})
+		return derived
+	}
+
+	lock := sync.Mutex{}
+	remaining := len(futures)
+	collect := func(i int) func(futureResult) {
+		return func(result futureResult) {
+			if result.err != nil {
+				derived.complete(result)
+				return
+			}
+
+			lock.Lock()
+			values[i] = result.value
+			remaining--
+			last := remaining == 0
+			lock.Unlock()
+
+			if last {
+				derived.complete(futureResult{value: values})
+			}
+		}
+	}
+
+	for i, future := range futures {
+		future.onComplete(collect(i))
+	}
+	return derived
+}
+
+// FirstCompleted returns a future with the result or failure of whichever future
+// completes first. Without any futures it fails with ErrNoFutures right away.
+func FirstCompleted(futures []Future) Future {
+	derived := newFuture(nil, 0)
+	if len(futures) == 0 {
+		derived.complete(futureResult{err: ErrNoFutures})
+		return derived
+	}
+
+	for _, future := range futures {
+		future.onComplete(func(result futureResult) { derived.complete(result) })
+	}
+	return derived
+}
@@ -2,6 +2,11 @@
 
 package goactors
 
+import (
+	"sync"
+	"time"
+)
+
 type ActorContext interface {
 	CreateActorFromFunc(factoryFunc func() Actor, name string) ActorRef
 	CreateProxyActorFromFunc(factoryFunc func() Actor, name string) ActorRef
@@ -12,8 +17,74 @@ type ActorContext interface {
 	Path() string
 	GetChild(name string) ActorRef
 	Stop(ref ActorRef)
+
+	// StopGracefully stops the actor after it processed every message already queued,
+	// waiting up to the timeout for it to stop. ErrStopTimeout is returned if it didn't.
+	StopGracefully(ref ActorRef, timeout time.Duration) error
+
+	// Kill stops the actor before any queued message, which are dropped. Like
+	// StopGracefully, it waits up to the timeout for the actor to stop.
+	Kill(ref ActorRef, timeout time.Duration) error
+
+	SetSupervisorStrategy(strategy SupervisorStrategy)
+
+	// Watch delivers a Terminated message to this actor once the watched actor stops.
+	// If the actor is already stopped, the message is delivered right away, with the
+	// cause StopUnknown.
+	Watch(ref ActorRef)
+	Unwatch(ref ActorRef)
+
+	// DeadLetters returns the system's sink for messages that could not be delivered
+	DeadLetters() *DeadLetters
+
+	// PipeToSelf delivers the future's result to this actor as mapFn(value, err), without
+	// blocking. The message keeps the sender of the message being handled now, so the
+	// actor can still reply to it. A nil mapFn delivers the value, or a Failure.
+	PipeToSelf(future Future, mapFn func(value interface{}, err error) interface{})
+
+	// Await runs the continuation on this actor's goroutine once the future completes,
+	// with the sender of the message being handled now. The actor keeps processing other
+	// messages in the meantime. Continuations still pending when the actor restarts are
+	// dropped.
+	Await(future Future, continuation func(context ActorContext, result interface{}, err error))
+
+	// Become replaces the current behavior. Messages go to the behavior instead of the
+	// actor's Receive until it is replaced again or the actor restarts.
+	Become(behavior ReceiveFunc)
+
+	// BecomeStacked makes the behavior current while keeping the previous one for Unbecome
+	BecomeStacked(behavior ReceiveFunc)
+
+	// Unbecome returns to the previous behavior, and to Receive once none is left
+	Unbecome()
+
+	// Stash sets the message being handled aside until UnstashAll. It fails with
+	// ErrStashFull once the stash is at its capacity.
+	Stash() error
+
+	// UnstashAll puts the stashed messages back in front of the mailbox, in the order
+	// they were stashed
+	UnstashAll()
+
+	// SetStashCapacity limits the number of stashed messages. Zero restores
+	// DefaultStashCapacity, less than zero means no limit.
+	SetStashCapacity(capacity int)
+
+	// SetReceiveTimeout delivers a ReceiveTimeout message whenever no message arrived for
+	// the timeout. Zero or less turns it off, as does a restart.
+	SetReceiveTimeout(timeout time.Duration)
+
+	// Timers returns the actor's named timers. They are cancelled when the actor stops or
+	// restarts.
+	Timers() *Timers
 }
 
+// ReceiveTimeout is delivered to an actor that got no message for its receive timeout
+type ReceiveTimeout struct{}
+
+// ReceiveFunc handles messages in place of an actor's Receive
+type ReceiveFunc func(context ActorContext, message interface{})
+
 type actorContextImpl struct {
 	path                 string
 	sender               ActorRef
@@ -21,6 +92,19 @@ type actorContextImpl struct {
 	self                 ActorRef
 	systemControlChannel chan<- interface{}
 	children             map[string]ActorRef
+	childrenLock         sync.Mutex // the root context is also used outside of the root actor
+	supervisorStrategy   SupervisorStrategy
+	watching             map[string]ActorRef
+	watchingLock         sync.Mutex // guarded like children, for the root context
+	deadLetters          *DeadLetters
+	incarnation          int // counts restarts, so that stale continuations are dropped
+	behaviors            []ReceiveFunc
+	message              interface{} // the message being handled, for Stash
+	stash                []actorMessage
+	stashCapacity        int
+	unstashed            []actorMessage
+	receiveTimeout       time.Duration
+	timers               *Timers
 }
 
 func (context *actorContextImpl) CreateActorFromFunc(factoryFunc func() Actor, name string) ActorRef {
@@ -49,7 +133,7 @@ func (context *actorContextImpl) createActor(request actorCreateRequest) ActorRe
 	var ref = <-responseChannel
 	if ref != nil {
 		// Context should only be updated on the goroutine owned by this actor
-		context.children[request.name] = ref
+		context.addChild(request.name, ref)
 	}
 
 	return ref
@@ -84,6 +168,9 @@ func (context *actorContextImpl) Path() string {
 }
 
 func (context *actorContextImpl) GetChild(name string) ActorRef {
+	context.childrenLock.Lock()
+	defer context.childrenLock.Unlock()
+
 	child, ok := context.children[name]
 	if ok {
 		return child
@@ -94,5 +181,164 @@ func (context *actorContextImpl) GetChild(name string) ActorRef {
 func (context *actorContextImpl) Stop(ref ActorRef) {
 	ref.Send(context.SelfRef(), poisonPillMessage{
 		resultChannel: nil,
+		reason:        StopReason{Cause: StopRequested},
 	})
 }
+
+// stopFailed stops the actor itself after the current message, failing with the
+// reason without asking the supervisor
+func (context *actorContextImpl) stopFailed(failure interface{}) {
+	context.self.Send(context.self, poisonPillMessage{
+		resultChannel: nil,
+		reason:        StopReason{Cause: StopFailed, Failure: failure},
+	})
+}
+
+// stopWatching forgets a watched actor. It reports whether the actor was still watched.
+func (context *actorContextImpl) stopWatching(ref ActorRef) bool {
+	context.watchingLock.Lock()
+	defer context.watchingLock.Unlock()
+
+	if _, ok := context.watching[ref.Path()]; !ok {
+		return false
+	}
+	delete(context.watching, ref.Path())
+	return true
+}
+
+func (context *actorContextImpl) SetSupervisorStrategy(strategy SupervisorStrategy) {
+	context.supervisorStrategy = strategy
+}
+
+func (context *actorContextImpl) Watch(ref ActorRef) {
+	context.watchingLock.Lock()
+	context.watching[ref.Path()] = ref
+	context.watchingLock.Unlock()
+
+	context.systemControlChannel <- actorWatchRequest{
+		watcher: context.self,
+		watched: ref,
+	}
+}
+
+func (context *actorContextImpl) Unwatch(ref ActorRef) {
+	context.watchingLock.Lock()
+	delete(context.watching, ref.Path())
+	context.watchingLock.Unlock()
+
+	context.systemControlChannel <- actorUnwatchRequest{
+		watcher: context.self,
+		watched: ref,
+	}
+}
+
+func (context *actorContextImpl) addChild(name string, ref ActorRef) {
+	context.childrenLock.Lock()
+	defer context.childrenLock.Unlock()
+	context.children[name] = ref
+}
+
+func (context *actorContextImpl) removeChild(ref ActorRef) {
+	context.childrenLock.Lock()
+	defer context.childrenLock.Unlock()
+	for name, child := range context.children {
+		if child == ref {
+			delete(context.children, name)
+		}
+	}
+}
+
+func (context *actorContextImpl) childRefs() []ActorRef {
+	context.childrenLock.Lock()
+	defer context.childrenLock.Unlock()
+	refs := make([]ActorRef, 0, len(context.children))
+	for _, child := range context.children {
+		refs = append(refs, child)
+	}
+	return refs
+}
+
+// takeChildren returns all children and forgets about them
+func (context *actorContextImpl) takeChildren() map[string]ActorRef {
+	context.childrenLock.Lock()
+	defer context.childrenLock.Unlock()
+	children := context.children
+	context.children = make(map[string]ActorRef)
+	return children
+}
+
+func (context *actorContextImpl) StopGracefully(ref ActorRef, timeout time.Duration) error {
+	ref.Send(context.SelfRef(), poisonPillMessage{
+		resultChannel: nil,
+		reason:        StopReason{Cause: StopRequested},
+	})
+	return context.awaitStop(ref, timeout)
+}
+
+func (context *actorContextImpl) Kill(ref ActorRef, timeout time.Duration) error {
+	kill(ref, context.SelfRef(), poisonPillMessage{
+		resultChannel: nil,
+		reason:        StopReason{Cause: StopKilled},
+		immediate:     true,
+	})
+	return context.awaitStop(ref, timeout)
+}
+
+func (context *actorContextImpl) DeadLetters() *DeadLetters {
+	return context.deadLetters
+}
+
+func (context *actorContextImpl) PipeToSelf(future Future, mapFn func(value interface{}, err error) interface{}) {
+	self := context.self
+	sender := context.sender
+	if mapFn == nil {
+		mapFn = pipedMessage
+	}
+
+	future.OnComplete(func(value interface{}, err error) {
+		sendAsync(self, sender, pipedResultMessage{mapFn: mapFn, value: value, err: err})
+	})
+}
+
+func (context *actorContextImpl) Await(future Future, continuation func(context ActorContext, result interface{}, err error)) {
+	self := context.self
+	sender := context.sender
+	incarnation := context.incarnation
+	future.OnComplete(func(value interface{}, err error) {
+		sendAsync(self, sender, continuationMessage{fn: continuation, value: value, err: err, incarnation: incarnation})
+	})
+}
+
+func (context *actorContextImpl) Become(behavior ReceiveFunc) {
+	if len(context.behaviors) == 0 {
+		context.behaviors = append(context.behaviors, behavior)
+		return
+	}
+	context.behaviors[len(context.behaviors)-1] = behavior
+}
+
+func (context *actorContextImpl) BecomeStacked(behavior ReceiveFunc) {
+	context.behaviors = append(context.behaviors, behavior)
+}
+
+func (context *actorContextImpl) Unbecome() {
+	if len(context.behaviors) > 0 {
+		context.behaviors = context.behaviors[:len(context.behaviors)-1]
+	}
+}
+
+// currentBehavior returns the behavior set by Become, or nil for the actor's Receive
+func (context *actorContextImpl) currentBehavior() ReceiveFunc {
+	if len(context.behaviors) == 0 {
+		return nil
+	}
+	return context.behaviors[len(context.behaviors)-1]
+}
+
+func (context *actorContextImpl) SetReceiveTimeout(timeout time.Duration) {
+	context.receiveTimeout = timeout
+}
+
+func (context *actorContextImpl) Timers() *Timers {
+	return context.timers
+}
@@ -0,0 +1,398 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MissedFirePolicy decides what happens to the fires of a cron schedule that were
+// missed, for example while the process was suspended
+type MissedFirePolicy int
+
+const (
+	// MissedFireOnce sends the message once for all missed fires
+	MissedFireOnce MissedFirePolicy = iota
+
+	// MissedFireSkip drops the missed fires
+	MissedFireSkip
+
+	// MissedFireAll sends the message once for every missed fire
+	MissedFireAll
+)
+
+func (policy MissedFirePolicy) String() string {
+	switch policy {
+	case MissedFireOnce:
+		return "MissedFireOnce"
+	case MissedFireSkip:
+		return "MissedFireSkip"
+	case MissedFireAll:
+		return "MissedFireAll"
+	default:
+		return "Unknown"
+	}
+}
+
+// DefaultMisfireThreshold is how late a fire may be before it counts as missed
+const DefaultMisfireThreshold = time.Second
+
+// Timers don't run while the process is suspended, so the wall clock is checked at
+// least this often
+const cronMaxWait = time.Minute
+
+// maxMissedFires limits the messages sent at once under MissedFireAll
+const maxMissedFires = 1000
+
+// Next gives up after this many steps, which is far more than any schedule needs
+const cronMaxSteps = 1 << 20
+
+// Daylight saving changes turn the clock back by less than this
+const cronMaxShift = 3 * time.Hour
+
+// CronOptions configures ScheduleCron
+type CronOptions struct {
+	// Time zone of expressions without a CRON_TZ prefix. Defaults to time.Local.
+	Location *time.Location
+
+	// What happens to fires that were missed
+	MissedFires MissedFirePolicy
+
+	// How late a fire may be before it counts as missed. Defaults to
+	// DefaultMisfireThreshold.
+	MisfireThreshold time.Duration
+}
+
+// CronSchedule is a parsed cron expression
+type CronSchedule struct {
+	seconds  uint64
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	anyDay   bool // the day of month is *, so only the weekday counts
+	anyWeek  bool // the weekday is *, so only the day of month counts
+	location *time.Location
+}
+
+type cronField struct {
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	secondField  = cronField{min: 0, max: 59}
+	minuteField  = cronField{min: 0, max: 59}
+	hourField    = cronField{min: 0, max: 23}
+	dayField     = cronField{min: 1, max: 31}
+	monthField   = cronField{min: 1, max: 12, names: map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}}
+	weekdayField = cronField{min: 0, max: 7, names: map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron parses a cron expression with the fields second, minute, hour, day of month,
+// month and day of week. The seconds may be left out, which means second 0. Fields take
+// *, ?, values, names like MON or JAN, ranges, lists and steps like */5. The descriptors
+// @yearly, @monthly, @weekly, @daily and @hourly are supported too. A CRON_TZ=<zone>
+// prefix sets the time zone, which is time.Local otherwise.
+func ParseCron(expression string) (*CronSchedule, error) {
+	return parseCron(expression, time.Local)
+}
+
+func parseCron(expression string, location *time.Location) (*CronSchedule, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("goactors: invalid cron expression %q: %s", expression, fmt.Sprintf(format, args...))
+	}
+
+	spec := strings.TrimSpace(expression)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		parts := strings.SplitN(spec, " ", 2)
+		zone := parts[0][strings.Index(parts[0], "=")+1:]
+		loaded, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, invalid("unknown time zone %s", zone)
+		}
+		location = loaded
+		if len(parts) < 2 {
+			return nil, invalid("no schedule after the time zone")
+		}
+		spec = strings.TrimSpace(parts[1])
+	}
+
+	if descriptor, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = descriptor
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) == 5 {
+		fields = append([]string{"0"}, fields...)
+	}
+	if len(fields) != 6 {
+		return nil, invalid("expected 5 or 6 fields, found %d", len(fields))
+	}
+
+	schedule := &CronSchedule{location: location}
+	targets := []*uint64{&schedule.seconds, &schedule.minutes, &schedule.hours, &schedule.days, &schedule.months, &schedule.weekdays}
+	definitions := []cronField{secondField, minuteField, hourField, dayField, monthField, weekdayField}
+	for i, field := range fields {
+		bits, err := definitions[i].parse(field)
+		if err != nil {
+			return nil, invalid("%s", err)
+		}
+		*targets[i] = bits
+	}
+
+	// Sunday is both 0 and 7
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	schedule.anyDay = isStar(fields[3])
+	schedule.anyWeek = isStar(fields[5])
+	return schedule, nil
+}
+
+func isWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// isStar reports whether a day field leaves the day open. As in Vixie cron, any field
+// starting with *, like */2, does, so that only the other day field restricts the days.
+func isStar(field string) bool {
+	return strings.HasPrefix(field, "*") || field == "?"
+}
+
+// parse returns the field's values as bits
+func (field cronField) parse(spec string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(spec, ",") {
+		low, high, step := field.min, field.max, 1
+
+		rangeSpec := part
+		hasStep := false
+		if slash := strings.Index(part, "/"); slash >= 0 {
+			hasStep = true
+			var err error
+			if step, err = strconv.Atoi(part[slash+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s", part)
+			}
+			rangeSpec = part[:slash]
+		}
+
+		if !isWildcard(rangeSpec) {
+			bounds := strings.SplitN(rangeSpec, "-", 2)
+			var err error
+			if low, err = field.value(bounds[0]); err != nil {
+				return 0, err
+			}
+
+			if len(bounds) == 2 {
+				if high, err = field.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if !hasStep {
+				// A single value, while N/step runs from N to the end
+				high = low
+			}
+		}
+
+		if low > high {
+			return 0, fmt.Errorf("invalid range %s", part)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func (field cronField) value(spec string) (int, error) {
+	if value, ok := field.names[strings.ToUpper(spec)]; ok {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(spec)
+	if err != nil || value < field.min || value > field.max {
+		return 0, fmt.Errorf("%s is not between %d and %d", spec, field.min, field.max)
+	}
+	return value, nil
+}
+
+// Next returns the first time of the schedule after the given time, in the schedule's
+// time zone. Times that a daylight saving change skips don't fire, and times it repeats
+// fire only the first time. It returns the zero time if there is none within the next
+// five years.
+func (schedule *CronSchedule) Next(after time.Time) time.Time {
+	t := after.In(schedule.location)
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+	// Every step moves forward, the limit only guards against looping forever
+	for steps := 0; steps < cronMaxSteps; steps++ {
+		if t.Year() > yearLimit {
+			return time.Time{}
+		}
+
+		switch {
+		case schedule.months&(1<<uint(t.Month())) == 0:
+			t = schedule.startOfDay(t.Year(), t.Month()+1, 1)
+		case !schedule.dayMatches(t):
+			t = schedule.startOfDay(t.Year(), t.Month(), t.Day()+1)
+		case schedule.hours&(1<<uint(t.Hour())) == 0:
+			t = nextHour(t)
+		case schedule.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		case schedule.seconds&(1<<uint(t.Second())) == 0:
+			t = t.Truncate(time.Second).Add(time.Second)
+		case repeatedWallTime(t):
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// startOfDay returns the first instant of the day, which is not midnight where a daylight
+// saving change skips it. Days and months out of range are normalized like time.Date does.
+func (schedule *CronSchedule) startOfDay(year int, month time.Month, day int) time.Time {
+	// Noon exists on every day, unlike midnight
+	noon := time.Date(year, month, day, 12, 0, 0, 0, schedule.location)
+	t := time.Date(noon.Year(), noon.Month(), noon.Day(), 0, 0, 0, 0, schedule.location)
+	for t.Day() != noon.Day() {
+		t = nextHour(t)
+	}
+	return t
+}
+
+// nextHour returns the start of the next hour on the wall clock. It counts the time that
+// passes rather than the hours on the clock, which a daylight saving change may skip or
+// repeat.
+func nextHour(t time.Time) time.Time {
+	elapsed := time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	return t.Add(time.Hour - elapsed)
+}
+
+// repeatedWallTime reports whether the wall clock already showed t once before, because
+// a daylight saving change turned it back
+func repeatedWallTime(t time.Time) bool {
+	_, offset := t.Zone()
+	_, before := t.Add(-cronMaxShift).Zone()
+	if before <= offset {
+		return false
+	}
+
+	earlier := t.Add(-time.Duration(before-offset) * time.Second)
+	return earlier.Hour() == t.Hour() && earlier.Minute() == t.Minute() && earlier.Second() == t.Second()
+}
+
+// dayMatches follows cron: if both the day of month and the weekday are restricted,
+// either one matching is enough
+func (schedule *CronSchedule) dayMatches(t time.Time) bool {
+	day := schedule.days&(1<<uint(t.Day())) != 0
+	weekday := schedule.weekdays&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case schedule.anyDay && schedule.anyWeek:
+		return true
+	case schedule.anyDay:
+		return weekday
+	case schedule.anyWeek:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// fire decides what a schedule that was due at due does when it wakes up at now: how many
+// messages it sends, following the options' missed fire policy, and when it is due next.
+// Nothing is sent before due. The next time is zero once the schedule has no more times.
+func (schedule *CronSchedule) fire(due time.Time, now time.Time, options CronOptions) (fires int, next time.Time) {
+	if now.Before(due) {
+		return 0, due
+	}
+
+	threshold := options.MisfireThreshold
+	if threshold <= 0 {
+		threshold = DefaultMisfireThreshold
+	}
+	if now.Sub(due) <= threshold {
+		return 1, schedule.Next(due)
+	}
+
+	switch options.MissedFires {
+	case MissedFireSkip:
+		fires = 0
+	case MissedFireAll:
+		for missed := due; !missed.IsZero() && !missed.After(now) && fires < maxMissedFires; fires++ {
+			missed = schedule.Next(missed)
+		}
+	default:
+		fires = 1
+	}
+	return fires, schedule.Next(now)
+}
+
+// ScheduleCron sends the message to the receiver at every time of the cron expression,
+// see ParseCron, until the task is cancelled
+func (scheduler *Scheduler) ScheduleCron(expression string, receiver ActorRef, message interface{}, options CronOptions) (*ScheduledTask, error) {
+	location := options.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	schedule, err := parseCron(expression, location)
+	if err != nil {
+		return nil, err
+	}
+
+	due := schedule.Next(time.Now())
+	if due.IsZero() {
+		return nil, fmt.Errorf("goactors: cron expression %q never fires", expression)
+	}
+
+	task := scheduler.schedule(cronWait(due, time.Now()), func() (time.Duration, bool) {
+		fires, next := schedule.fire(due, time.Now(), options)
+		for i := 0; i < fires; i++ {
+			receiver.Send(nil, message)
+		}
+
+		if next.IsZero() {
+			return 0, false
+		}
+		due = next
+		return cronWait(due, time.Now()), true
+	})
+	return task, nil
+}
+
+func cronWait(due time.Time, now time.Time) time.Duration {
+	wait := due.Sub(now)
+	if wait > cronMaxWait {
+		return cronMaxWait
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// ScheduleCron sends the message to the receiver at every time of the cron expression,
+// see ParseCron, until the task is cancelled. It uses the zero CronOptions, which send
+// missed fires once; Scheduler().ScheduleCron takes other options.
+func (system *ActorSystem) ScheduleCron(expression string, receiver ActorRef, message interface{}) (*ScheduledTask, error) {
+	return system.scheduler.ScheduleCron(expression, receiver, message, CronOptions{})
+}
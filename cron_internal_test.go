@@ -0,0 +1,46 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"testing"
+	"time"
+)
+
+// fire is unexported, so it is tested from inside the package
+func TestCronScheduleFire(t *testing.T) {
+	schedule, err := parseCron("0 * * * * *", time.UTC)
+	if err != nil {
+		t.Fatalf("Expected the expression to parse, received %v", err)
+	}
+
+	due := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	minute := func(n int) time.Time { return due.Add(time.Duration(n) * time.Minute) }
+
+	// The process was suspended for a while after the fire at 10:00 was due
+	suspended := minute(5).Add(30 * time.Second)
+	cases := []struct {
+		name     string
+		now      time.Time
+		options  CronOptions
+		fires    int
+		expected time.Time
+	}{
+		{"early", due.Add(-time.Second), CronOptions{}, 0, due},
+		{"on time", due, CronOptions{}, 1, minute(1)},
+		{"within the threshold", due.Add(time.Second), CronOptions{MissedFires: MissedFireSkip}, 1, minute(1)},
+		{"past a custom threshold", due.Add(2 * time.Second), CronOptions{MissedFires: MissedFireSkip, MisfireThreshold: time.Second}, 0, minute(1)},
+		{"within a custom threshold", due.Add(2 * time.Second), CronOptions{MissedFires: MissedFireSkip, MisfireThreshold: 5 * time.Second}, 1, minute(1)},
+		{"once", suspended, CronOptions{MissedFires: MissedFireOnce}, 1, minute(6)},
+		{"skip", suspended, CronOptions{MissedFires: MissedFireSkip}, 0, minute(6)},
+		{"all", suspended, CronOptions{MissedFires: MissedFireAll}, 6, minute(6)},
+		{"all capped", minute(5000), CronOptions{MissedFires: MissedFireAll}, maxMissedFires, minute(5001)},
+	}
+
+	for _, c := range cases {
+		fires, next := schedule.fire(due, c.now, c.options)
+		if fires != c.fires || !next.Equal(c.expected) {
+			t.Errorf("%s: expected %d fires and next %v, received %d and %v", c.name, c.fires, c.expected, fires, next)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DeadLetter is a message that could not be delivered to its recipient
+type DeadLetter struct {
+	Sender    ActorRef
+	Recipient ActorRef
+	Message   interface{}
+
+	// Unhandled is set if the recipient was running but did not handle the message
+	Unhandled bool
+}
+
+// DeadLetters is the system-wide sink for undeliverable messages: messages sent to
+// stopped actors, messages left in the mailbox of a stopped actor and replies to
+// futures that already completed. Messages that a running actor did not handle end
+// up here as well.
+type DeadLetters struct {
+	lock        sync.RWMutex
+	logging     bool
+	nextID      int
+	subscribers map[int]func(DeadLetter)
+}
+
+func newDeadLetters() *DeadLetters {
+	return &DeadLetters{
+		subscribers: make(map[int]func(DeadLetter)),
+	}
+}
+
+// Subscribe calls the handler for every dead letter until the returned function is
+// called. Handlers run on the goroutine that failed to deliver the message, so they
+// should hand the letter off instead of blocking.
+func (deadLetters *DeadLetters) Subscribe(handler func(DeadLetter)) (unsubscribe func()) {
+	deadLetters.lock.Lock()
+	defer deadLetters.lock.Unlock()
+
+	id := deadLetters.nextID
+	deadLetters.nextID++
+	deadLetters.subscribers[id] = handler
+
+	return func() {
+		deadLetters.lock.Lock()
+		defer deadLetters.lock.Unlock()
+		delete(deadLetters.subscribers, id)
+	}
+}
+
+// SetLogging turns printing every dead letter to stdout on or off
+func (deadLetters *DeadLetters) SetLogging(enabled bool) {
+	deadLetters.lock.Lock()
+	defer deadLetters.lock.Unlock()
+	deadLetters.logging = enabled
+}
+
+// Publish hands a dead letter to all subscribers
+func (deadLetters *DeadLetters) Publish(letter DeadLetter) {
+	if deadLetters == nil {
+		return
+	}
+
+	deadLetters.lock.RLock()
+	logging := deadLetters.logging
+	handlers := make([]func(DeadLetter), 0, len(deadLetters.subscribers))
+	for _, handler := range deadLetters.subscribers {
+		handlers = append(handlers, handler)
+	}
+	deadLetters.lock.RUnlock()
+
+	if logging {
+		kind := "Dead letter"
+		if letter.Unhandled {
+			kind = "Unhandled message"
+		}
+		fmt.Printf("%s from %s to %s: %v\n", kind, refPath(letter.Sender), refPath(letter.Recipient), letter.Message)
+	}
+
+	for _, handler := range handlers {
+		handler(letter)
+	}
+}
+
+// asker is a sender that waits for replies, a future or a stream
+type asker interface {
+	// failAsk ends the wait once no reply can come anymore
+	failAsk(err error)
+}
+
+// undeliverable publishes a message that could not be delivered. A future or stream
+// waiting for a reply to it fails right away instead of running into its timeout.
+// Internal wrappers are published as the message they carry.
+func (deadLetters *DeadLetters) undeliverable(letter DeadLetter) {
+	if isSystemMessage(letter.Message) {
+		return
+	}
+
+	letter.Message = unwrapMessage(letter.Message)
+
+	if asker, ok := letter.Sender.(asker); ok {
+		asker.failAsk(ErrActorStopped)
+	}
+	deadLetters.Publish(letter)
+}
+
+func refPath(ref ActorRef) string {
+	if ref == nil {
+		return "<nobody>"
+	}
+	return ref.Path()
+}
+
+// unhandled publishes a message that a running actor did not handle. A future or stream
+// waiting for a reply to it fails with ErrUnhandledMessage.
+func (deadLetters *DeadLetters) unhandled(letter DeadLetter) {
+	if asker, ok := letter.Sender.(asker); ok {
+		asker.failAsk(ErrUnhandledMessage)
+	}
+	letter.Unhandled = true
+	deadLetters.Publish(letter)
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"fmt"
+	"time"
+)
+
+// FSMState names a state of an FSMActor
+type FSMState string
+
+// FSMEvent is what a state handler gets: the message and the current state data
+type FSMEvent struct {
+	Message interface{}
+	Data    interface{}
+}
+
+// StateTimeout is the message a state handler gets when the state's timeout passed
+// without any other message
+type StateTimeout struct{}
+
+// FSMStateFunc handles a message in a state and returns the transition to make. Nil
+// means the message was not handled.
+type FSMStateFunc func(context ActorContext, event FSMEvent) *FSMTransition
+
+// FSMTransition is the result of a state handler, built with Goto or Stay
+type FSMTransition struct {
+	state   FSMState
+	stay    bool
+	data    interface{}
+	hasData bool
+	timeout time.Duration
+}
+
+// Goto moves the FSM to the state, which must have been set up with When. Going to an
+// undefined state panics, so the supervisor handles it like any other failure. Going to
+// the current state works like Stay and does not call the OnTransition callbacks.
+func Goto(state FSMState) *FSMTransition {
+	return &FSMTransition{state: state}
+}
+
+// Stay keeps the FSM in its current state. The state timeout starts over.
+func Stay() *FSMTransition {
+	return &FSMTransition{stay: true}
+}
+
+// Using replaces the state data
+func (transition *FSMTransition) Using(data interface{}) *FSMTransition {
+	transition.data = data
+	transition.hasData = true
+	return transition
+}
+
+// ForMax overrides the state timeout of the next state for this transition
+func (transition *FSMTransition) ForMax(timeout time.Duration) *FSMTransition {
+	transition.timeout = timeout
+	return transition
+}
+
+type fsmStateDefinition struct {
+	timeout time.Duration
+	handler FSMStateFunc
+}
+
+// fsmStateTimeoutKey is the key of the state timeout among the actor's timers
+type fsmStateTimeoutKey struct{}
+
+// FSMActor is an Actor driven by state handlers. Set it up with When and OnTransition
+// in the factory passed to CreateActorFromFunc. Actors that embed it and implement
+// OnStart themselves have to call the FSMActor's as well.
+type FSMActor struct {
+	states      map[FSMState]fsmStateDefinition
+	unhandled   FSMStateFunc
+	transitions []func(context ActorContext, from FSMState, to FSMState)
+	state       FSMState
+	data        interface{}
+	failure     error
+}
+
+// NewFSMActor returns an FSM starting in the initial state with the data
+func NewFSMActor(initial FSMState, data interface{}) *FSMActor {
+	return &FSMActor{
+		states: make(map[FSMState]fsmStateDefinition),
+		state:  initial,
+		data:   data,
+	}
+}
+
+// When sets the handler for a state. If no message arrives for the timeout while in the
+// state, the handler gets a StateTimeout. Zero means no timeout.
+func (fsm *FSMActor) When(state FSMState, timeout time.Duration, handler FSMStateFunc) *FSMActor {
+	fsm.states[state] = fsmStateDefinition{timeout: timeout, handler: handler}
+	return fsm
+}
+
+// WhenUnhandled sets the handler for messages the current state did not handle. Without
+// one, they are published to the dead letters as unhandled and asks with them fail with
+// ErrUnhandledMessage.
+func (fsm *FSMActor) WhenUnhandled(handler FSMStateFunc) *FSMActor {
+	fsm.unhandled = handler
+	return fsm
+}
+
+// OnTransition adds a callback for every change of state. The new state data is already
+// set when it is called. Staying in a state, even through Goto, is not a change.
+func (fsm *FSMActor) OnTransition(callback func(context ActorContext, from FSMState, to FSMState)) *FSMActor {
+	fsm.transitions = append(fsm.transitions, callback)
+	return fsm
+}
+
+// StateName returns the current state
+func (fsm *FSMActor) StateName() FSMState {
+	return fsm.state
+}
+
+// StateData returns the current state data
+func (fsm *FSMActor) StateData() interface{} {
+	return fsm.data
+}
+
+// OnStart starts the timeout of the initial state. If the initial state was never set
+// up, the FSM stops itself with StopFailed and an error as the failure. Restarting would
+// not help, so the supervisor is not asked.
+func (fsm *FSMActor) OnStart(context ActorContext) {
+	definition, ok := fsm.states[fsm.state]
+	if !ok {
+		fsm.failure = fmt.Errorf("goactors: FSM initial state %q is not defined", fsm.state)
+		context.(*actorContextImpl).stopFailed(fsm.failure)
+		return
+	}
+	fsm.startTimeout(context, definition.timeout)
+}
+
+func (fsm *FSMActor) OnStop() {
+	// The state timeout is one of the actor's timers, which are cancelled on stop
+}
+
+func (fsm *FSMActor) Receive(context ActorContext, message interface{}) {
+	if fsm.failure != nil {
+		// Messages sent before the FSM failed to start are not handled
+		context.DeadLetters().unhandled(DeadLetter{Sender: context.SenderRef(), Recipient: context.SelfRef(), Message: message})
+		return
+	}
+
+	context.Timers().Cancel(fsmStateTimeoutKey{})
+
+	event := FSMEvent{Message: message, Data: fsm.data}
+	transition := fsm.definition(fsm.state).handler(context, event)
+	if transition == nil && fsm.unhandled != nil {
+		transition = fsm.unhandled(context, event)
+	}
+
+	if transition == nil {
+		if _, ok := message.(StateTimeout); !ok {
+			// An asker fails right away instead of waiting for its timeout
+			context.DeadLetters().unhandled(DeadLetter{Sender: context.SenderRef(), Recipient: context.SelfRef(), Message: message})
+		}
+		transition = Stay()
+	}
+
+	fsm.apply(context, transition)
+}
+
+func (fsm *FSMActor) apply(context ActorContext, transition *FSMTransition) {
+	from := fsm.state
+	definition := fsm.definition(from)
+	if !transition.stay {
+		definition = fsm.definition(transition.state)
+		fsm.state = transition.state
+	}
+	if transition.hasData {
+		fsm.data = transition.data
+	}
+
+	timeout := definition.timeout
+	if transition.timeout > 0 {
+		timeout = transition.timeout
+	}
+	fsm.startTimeout(context, timeout)
+
+	if from != fsm.state {
+		for _, callback := range fsm.transitions {
+			callback(context, from, fsm.state)
+		}
+	}
+}
+
+// definition returns the state's definition and panics for states that were never set up
+func (fsm *FSMActor) definition(state FSMState) fsmStateDefinition {
+	definition, ok := fsm.states[state]
+	if !ok {
+		panic(fmt.Sprintf("goactors: FSM state %q is not defined", state))
+	}
+	return definition
+}
+
+func (fsm *FSMActor) startTimeout(context ActorContext, timeout time.Duration) {
+	if timeout > 0 {
+		context.Timers().StartSingle(fsmStateTimeoutKey{}, StateTimeout{}, timeout)
+	}
+}
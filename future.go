@@ -1,64 +1,251 @@
 package goactors
 
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrAskTimeout is returned when no reply arrived in time
+	ErrAskTimeout = errors.New("goactors: timed out waiting for a reply")
+)
+
+// Failure is a reply that fails the future with Err instead of completing it with a value
+type Failure struct {
+	Err error
+}
+
+// DefaultAskTimeout is the timeout used by Ask. Once it passes the future fails with
+// ErrAskTimeout, so nobody waits forever on an actor that never replies.
+const DefaultAskTimeout = 30 * time.Second
+
 // Future represents a future result that has yet to be computed
 type Future interface {
 	// Blocks thread to get current result from the future
 	GetResult() interface{}
 
+	// Blocks until there is a result. Fails with the future's own failure.
+	Result() (interface{}, error)
+
+	// Blocks up to the timeout to get the result. Fails with ErrAskTimeout if there was
+	// no result in time, or with the future's own failure.
+	GetResultTimeout(timeout time.Duration) (interface{}, error)
+
+	// Blocks until there is a result or the context is done
+	Await(ctx context.Context) (interface{}, error)
+
+	// Calls the callback with the result once there is one, right away if there already is.
+	// The callback runs on the goroutine that completes the future and should not block.
+	OnComplete(callback func(value interface{}, err error))
+
 	// Forwards the future's reslult to the specified actor. This is a non-blocking call
 	ForwardResult(sender ActorRef, target ActorRef)
+
+	// Sends the result to the target as coming from the sender once there is one, or a
+	// Failure if the future failed. This is a non-blocking call.
+	PipeTo(sender ActorRef, target ActorRef)
+
+	// Returns a future with the result passed through fn. Failures are passed on as is.
+	Map(fn func(value interface{}) interface{}) Future
+
+	// Returns a future completed by the future that fn returns for the result. Failures
+	// are passed on as is.
+	FlatMap(fn func(value interface{}) Future) Future
+
+	// Then is an alias for FlatMap
+	Then(fn func(value interface{}) Future) Future
+
+	// Returns a future that turns a failure into the value returned by fn. Results are
+	// passed on as is.
+	Recover(fn func(err error) interface{}) Future
+
+	onComplete(callback func(futureResult))
+}
+
+type futureResult struct {
+	value interface{}
+	err   error
 }
 
 type futureImpl struct {
-	writeChannel chan<- actorMessage
-	readChannel  <-chan actorMessage
+	lock        sync.Mutex
+	done        chan struct{}
+	result      futureResult
+	completed   bool
+	callbacks   []func(futureResult)
+	timer       *time.Timer
+	deadLetters *DeadLetters
 }
 
-func newFuture() *futureImpl {
-	c := make(chan actorMessage, 1)
-	return &futureImpl{
-		writeChannel: c,
-		readChannel:  c,
+func newFuture(deadLetters *DeadLetters, timeout time.Duration) *futureImpl {
+	future := &futureImpl{
+		done:        make(chan struct{}),
+		deadLetters: deadLetters,
 	}
+
+	if timeout > 0 {
+		future.lock.Lock()
+		future.timer = time.AfterFunc(timeout, func() {
+			future.complete(futureResult{err: ErrAskTimeout})
+		})
+		future.lock.Unlock()
+	}
+	return future
+}
+
+// complete stores the result unless the future already has one, and runs the callbacks
+func (future *futureImpl) complete(result futureResult) bool {
+	future.lock.Lock()
+	if future.completed {
+		future.lock.Unlock()
+		return false
+	}
+
+	future.result = result
+	future.completed = true
+	close(future.done)
+
+	if future.timer != nil {
+		future.timer.Stop()
+		future.timer = nil
+	}
+
+	callbacks := future.callbacks
+	future.callbacks = nil
+	future.lock.Unlock()
+
+	for _, callback := range callbacks {
+		callback(result)
+	}
+	return true
+}
+
+// onComplete calls the callback once the future has a result, right away if it already has
+func (future *futureImpl) onComplete(callback func(futureResult)) {
+	future.lock.Lock()
+	if !future.completed {
+		future.callbacks = append(future.callbacks, callback)
+		future.lock.Unlock()
+		return
+	}
+
+	result := future.result
+	future.lock.Unlock()
+	callback(result)
+}
+
+// wait returns the result once the future is completed
+func (future *futureImpl) wait() futureResult {
+	<-future.done
+	future.lock.Lock()
+	defer future.lock.Unlock()
+	return future.result
+}
+
+func (future *futureImpl) failAsk(err error) {
+	future.complete(futureResult{err: err})
+}
+
+func (future *futureImpl) OnComplete(callback func(value interface{}, err error)) {
+	future.onComplete(func(result futureResult) { callback(result.value, result.err) })
 }
 
 func (future *futureImpl) Path() string {
 	return "future"
 }
 
+func replyResult(message interface{}) futureResult {
+	if failure, ok := message.(Failure); ok {
+		return futureResult{err: failure.Err}
+	}
+	return futureResult{value: message}
+}
+
 func (future *futureImpl) Send(sender ActorRef, message interface{}) {
-	if future.writeChannel == nil {
-		return
+	if !future.complete(replyResult(message)) {
+		// Only the first reply completes the future
+		future.deadLetters.Publish(DeadLetter{Sender: sender, Recipient: future, Message: message})
+	}
+}
+
+func (future *futureImpl) TrySend(sender ActorRef, message interface{}) error {
+	if !future.complete(replyResult(message)) {
+		return ErrActorStopped
 	}
+	return nil
+}
 
-	future.writeChannel <- actorMessage{sender: sender, message: message}
-	close(future.writeChannel)
-	future.writeChannel = nil
+func (future *futureImpl) SendContext(ctx context.Context, sender ActorRef, message interface{}) error {
+	// Completing a future never blocks
+	return future.TrySend(sender, message)
 }
 
 func (future *futureImpl) Ask(message interface{}) Future {
 	panic("Should not `Ask` on a future")
 }
 
+func (future *futureImpl) AskWithTimeout(message interface{}, timeout time.Duration) Future {
+	panic("Should not `Ask` on a future")
+}
+
+func (future *futureImpl) AskStream(message interface{}) Stream {
+	panic("Should not `Ask` on a future")
+}
+
 func (future *futureImpl) GetResult() interface{} {
-	result, ok := <-future.readChannel
-	if !ok {
-		// Future has been handled
-		return nil
-	}
+	return future.wait().value
+}
 
-	return result.message
+func (future *futureImpl) Result() (interface{}, error) {
+	result := future.wait()
+	return result.value, result.err
 }
 
-func (future *futureImpl) doForwardResult(sender ActorRef, target ActorRef) {
-	result := future.GetResult()
-	if result == nil {
-		return
+func (future *futureImpl) GetResultTimeout(timeout time.Duration) (interface{}, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-future.done:
+		result := future.wait()
+		return result.value, result.err
+	case <-timer.C:
+		return nil, ErrAskTimeout
 	}
+}
 
-	target.Send(sender, result)
+func (future *futureImpl) Await(ctx context.Context) (interface{}, error) {
+	select {
+	case <-future.done:
+		result := future.wait()
+		return result.value, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 func (future *futureImpl) ForwardResult(sender ActorRef, target ActorRef) {
-	go future.doForwardResult(sender, target)
+	future.onComplete(func(result futureResult) {
+		if result.value == nil {
+			return
+		}
+
+		sendAsync(target, sender, result.value)
+	})
+}
+
+// pipedMessage is the message that delivers a result to an actor
+func pipedMessage(value interface{}, err error) interface{} {
+	if err != nil {
+		return Failure{Err: err}
+	}
+	return value
+}
+
+func (future *futureImpl) PipeTo(sender ActorRef, target ActorRef) {
+	future.onComplete(func(result futureResult) {
+		sendAsync(target, sender, pipedMessage(result.value, result.err))
+	})
 }
@@ -1 +1,3 @@
 module github.com/cgrunewald/goactors
+
+go 1.18
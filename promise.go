@@ -0,0 +1,74 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"context"
+	"time"
+)
+
+// Promise is a future that is completed from the outside. As an ActorRef it can be handed
+// to actors, which complete it by sending to it just like replying to an Ask. Plain Go
+// code completes it with Complete or Fail. Only the first result counts.
+type Promise struct {
+	future *futureImpl
+}
+
+// NewPromise returns a promise without a timeout
+func NewPromise() *Promise {
+	return &Promise{future: newFuture(nil, 0)}
+}
+
+// Future returns the view of the promise's result
+func (promise *Promise) Future() Future {
+	return promise.future
+}
+
+// Complete completes the promise with the value. It reports whether the promise was
+// still open.
+func (promise *Promise) Complete(value interface{}) bool {
+	return promise.future.complete(futureResult{value: value})
+}
+
+// Fail fails the promise with the error. It reports whether the promise was still open.
+func (promise *Promise) Fail(err error) bool {
+	return promise.future.complete(futureResult{err: err})
+}
+
+func (promise *Promise) failAsk(err error) {
+	promise.Fail(err)
+}
+
+func (promise *Promise) Path() string {
+	return "promise"
+}
+
+func (promise *Promise) Send(sender ActorRef, message interface{}) {
+	if !promise.future.complete(replyResult(message)) {
+		promise.future.deadLetters.Publish(DeadLetter{Sender: sender, Recipient: promise, Message: message})
+	}
+}
+
+func (promise *Promise) TrySend(sender ActorRef, message interface{}) error {
+	if !promise.future.complete(replyResult(message)) {
+		return ErrActorStopped
+	}
+	return nil
+}
+
+func (promise *Promise) SendContext(ctx context.Context, sender ActorRef, message interface{}) error {
+	// Completing a promise never blocks
+	return promise.TrySend(sender, message)
+}
+
+func (promise *Promise) Ask(message interface{}) Future {
+	panic("Should not `Ask` on a promise")
+}
+
+func (promise *Promise) AskWithTimeout(message interface{}, timeout time.Duration) Future {
+	panic("Should not `Ask` on a promise")
+}
+
+func (promise *Promise) AskStream(message interface{}) Stream {
+	panic("Should not `Ask` on a promise")
+}
@@ -2,15 +2,102 @@
 
 package goactors
 
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrMailboxFull is returned by TrySend when the message can't be enqueued right away
+	ErrMailboxFull = errors.New("goactors: mailbox is full")
+
+	// ErrActorStopped is returned when the recipient no longer accepts messages
+	ErrActorStopped = errors.New("goactors: actor is stopped")
+
+	// ErrUnhandledMessage is returned when the recipient is running but did not handle the message
+	ErrUnhandledMessage = errors.New("goactors: message was not handled")
+)
+
 type ActorRef interface {
 	Path() string
 	Send(sender ActorRef, message interface{})
 	Ask(message interface{}) Future
+
+	// AskWithTimeout is Ask with its own timeout instead of DefaultAskTimeout. A timeout of
+	// zero or less never expires.
+	AskWithTimeout(message interface{}, timeout time.Duration) Future
+
+	// AskStream sends the message and returns a stream of all replies to it, until the
+	// actor replies with EndOfStream
+	AskStream(message interface{}) Stream
+
+	// TrySend enqueues the message without blocking. It fails with ErrMailboxFull or
+	// ErrActorStopped instead of waiting for room in the mailbox. A nil error only means
+	// the message was enqueued: if the actor stops before processing it, it goes to the
+	// dead letters like any other message left in the mailbox.
+	TrySend(sender ActorRef, message interface{}) error
+
+	// SendContext blocks like Send until the message is enqueued, unless the context is
+	// done first. It fails with ErrActorStopped or the context's error.
+	SendContext(ctx context.Context, sender ActorRef, message interface{}) error
 }
 
 type actorRef struct {
 	name           string
 	messageChannel chan<- actorMessage
+	killChannel    chan<- poisonPillMessage
+	status         *mailboxStatus
+	deadLetters    *DeadLetters
+	unbounded      bool // the proxy buffers any number of messages
+}
+
+// mailboxStatus is shared by an actor and its refs. Senders hold the read lock while
+// they enqueue, so once the actor closed its mailbox no message can slip in after the
+// remaining ones were moved to the dead letters.
+type mailboxStatus struct {
+	lock    sync.RWMutex
+	closed  bool
+	closing chan struct{} // releases senders blocked on a full mailbox
+	stopped chan struct{} // closed once the mailbox was drained
+}
+
+func newMailboxStatus() *mailboxStatus {
+	return &mailboxStatus{
+		closing: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// stoppedChannel returns a channel that is closed once the actor behind the ref stopped.
+// Refs that are not backed by an actor never report being stopped.
+func stoppedChannel(ref ActorRef) <-chan struct{} {
+	if actor, ok := ref.(*actorRef); ok {
+		return actor.status.stopped
+	}
+	return nil
+}
+
+// sendAsync sends the message without waiting. Send may block on a full mailbox, which
+// must not hold up whoever completes a future or cancels a stream.
+func sendAsync(target ActorRef, sender ActorRef, message interface{}) {
+	go target.Send(sender, message)
+}
+
+// kill delivers the poison pill ahead of any queued messages. Refs that are not backed
+// by an actor get the pill as a regular message.
+func kill(ref ActorRef, sender ActorRef, pill poisonPillMessage) {
+	actor, ok := ref.(*actorRef)
+	if !ok {
+		ref.Send(sender, pill)
+		return
+	}
+
+	select {
+	case actor.killChannel <- pill:
+	case <-actor.status.closing:
+	}
 }
 
 func (self *actorRef) Path() string {
@@ -18,11 +105,96 @@ func (self *actorRef) Path() string {
 }
 
 func (self *actorRef) Send(sender ActorRef, message interface{}) {
-	self.messageChannel <- actorMessage{sender: sender, message: message}
+	self.status.lock.RLock()
+	defer self.status.lock.RUnlock()
+
+	if !self.status.closed {
+		select {
+		case self.messageChannel <- actorMessage{sender: sender, message: message}:
+			return
+		case <-self.status.closing:
+		}
+	}
+
+	// Nobody is left to receive the message
+	self.deadLetters.undeliverable(DeadLetter{Sender: sender, Recipient: self, Message: message})
+}
+
+// stopping reports whether the actor stopped taking messages. The caller holds the read
+// lock. It is checked before sending, since a select picks a free mailbox slot over a
+// closed closing channel at random.
+func (self *actorRef) stopping() bool {
+	if self.status.closed {
+		return true
+	}
+
+	select {
+	case <-self.status.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+func (self *actorRef) TrySend(sender ActorRef, message interface{}) error {
+	self.status.lock.RLock()
+	defer self.status.lock.RUnlock()
+
+	if self.stopping() {
+		return ErrActorStopped
+	}
+
+	actorMsg := actorMessage{sender: sender, message: message}
+	if self.unbounded {
+		// The proxy is never full, it may just be busy forwarding for a moment
+		select {
+		case self.messageChannel <- actorMsg:
+			return nil
+		case <-self.status.closing:
+			return ErrActorStopped
+		}
+	}
+
+	select {
+	case self.messageChannel <- actorMsg:
+		return nil
+	case <-self.status.closing:
+		return ErrActorStopped
+	default:
+		return ErrMailboxFull
+	}
+}
+
+func (self *actorRef) SendContext(ctx context.Context, sender ActorRef, message interface{}) error {
+	self.status.lock.RLock()
+	defer self.status.lock.RUnlock()
+
+	if self.stopping() {
+		return ErrActorStopped
+	}
+
+	select {
+	case self.messageChannel <- actorMessage{sender: sender, message: message}:
+		return nil
+	case <-self.status.closing:
+		return ErrActorStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (ref *actorRef) Ask(message interface{}) Future {
-	future := newFuture()
-	ref.messageChannel <- actorMessage{sender: future, message: message}
+	return ref.AskWithTimeout(message, DefaultAskTimeout)
+}
+
+func (ref *actorRef) AskWithTimeout(message interface{}, timeout time.Duration) Future {
+	future := newFuture(ref.deadLetters, timeout)
+	ref.Send(future, message)
 	return future
 }
+
+func (ref *actorRef) AskStream(message interface{}) Stream {
+	stream := newStream(ref, ref.deadLetters, DefaultAskTimeout)
+	ref.Send(stream, message)
+	return stream
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"sync"
+	"time"
+)
+
+// Scheduler sends messages to actors after a delay. Everything still scheduled is
+// cancelled when the actor system shuts down.
+type Scheduler struct {
+	lock     sync.Mutex
+	tasks    map[*ScheduledTask]struct{}
+	shutdown bool
+}
+
+// ScheduledTask is a scheduled message that can be cancelled
+type ScheduledTask struct {
+	lock      sync.Mutex
+	timer     *time.Timer
+	cancelled bool
+	scheduler *Scheduler
+}
+
+func newScheduler() *Scheduler {
+	return &Scheduler{
+		tasks: make(map[*ScheduledTask]struct{}),
+	}
+}
+
+// ScheduleOnce sends the message to the receiver once the delay has passed
+func (scheduler *Scheduler) ScheduleOnce(delay time.Duration, receiver ActorRef, message interface{}) *ScheduledTask {
+	return scheduler.schedule(delay, func() (time.Duration, bool) {
+		receiver.Send(nil, message)
+		return 0, false
+	})
+}
+
+// ScheduleRepeatedly sends the message to the receiver after the initial delay and then
+// every interval, until it is cancelled. It panics if the interval is not positive.
+func (scheduler *Scheduler) ScheduleRepeatedly(initialDelay time.Duration, interval time.Duration, receiver ActorRef, message interface{}) *ScheduledTask {
+	if interval <= 0 {
+		panic("goactors: non-positive interval for ScheduleRepeatedly")
+	}
+
+	return scheduler.schedule(initialDelay, func() (time.Duration, bool) {
+		receiver.Send(nil, message)
+		return interval, true
+	})
+}
+
+// schedule calls fire after the delay, and again after every delay it returns until it
+// returns false
+func (scheduler *Scheduler) schedule(delay time.Duration, fire func() (time.Duration, bool)) *ScheduledTask {
+	task := &ScheduledTask{scheduler: scheduler}
+
+	scheduler.lock.Lock()
+	if scheduler.shutdown {
+		scheduler.lock.Unlock()
+		task.cancelled = true
+		return task
+	}
+	scheduler.tasks[task] = struct{}{}
+	scheduler.lock.Unlock()
+
+	var run func()
+	run = func() {
+		if !task.isActive() {
+			return
+		}
+
+		next, again := fire()
+		if !again {
+			task.Cancel()
+			return
+		}
+		task.start(next, run)
+	}
+	task.start(delay, run)
+	return task
+}
+
+func (scheduler *Scheduler) remove(task *ScheduledTask) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	delete(scheduler.tasks, task)
+}
+
+// stop cancels every task. Nothing can be scheduled afterwards.
+func (scheduler *Scheduler) stop() {
+	scheduler.lock.Lock()
+	scheduler.shutdown = true
+	tasks := scheduler.tasks
+	scheduler.tasks = make(map[*ScheduledTask]struct{})
+	scheduler.lock.Unlock()
+
+	for task := range tasks {
+		task.cancel()
+	}
+}
+
+// Cancel stops the task. It reports whether the task was still scheduled. A message the
+// task is sending at that moment may still arrive afterwards, since waiting for the send
+// could block on the receiver's full mailbox. Actor timers drop such messages.
+func (task *ScheduledTask) Cancel() bool {
+	if !task.cancel() {
+		return false
+	}
+
+	task.scheduler.remove(task)
+	return true
+}
+
+// start runs fn after the delay, unless the task was cancelled in the meantime
+func (task *ScheduledTask) start(delay time.Duration, fn func()) {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	if !task.cancelled {
+		task.timer = time.AfterFunc(delay, fn)
+	}
+}
+
+func (task *ScheduledTask) cancel() bool {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+
+	if task.cancelled {
+		return false
+	}
+
+	task.cancelled = true
+	if task.timer != nil {
+		task.timer.Stop()
+	}
+	return true
+}
+
+func (task *ScheduledTask) isActive() bool {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	return !task.cancelled
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"errors"
+)
+
+// ErrStashFull is returned by Stash once the stash holds as many messages as its capacity
+var ErrStashFull = errors.New("goactors: stash is full")
+
+// DefaultStashCapacity is the stash capacity of actors that did not set their own
+const DefaultStashCapacity = 1000
+
+func (context *actorContextImpl) Stash() error {
+	capacity := context.stashCapacity
+	if capacity == 0 {
+		capacity = DefaultStashCapacity
+	}
+	if capacity > 0 && len(context.stash) >= capacity {
+		return ErrStashFull
+	}
+
+	context.stash = append(context.stash, actorMessage{sender: context.sender, message: context.message})
+	return nil
+}
+
+func (context *actorContextImpl) UnstashAll() {
+	// Ahead of messages unstashed earlier that were not processed yet
+	context.unstashed = append(context.stash, context.unstashed...)
+	context.stash = nil
+}
+
+func (context *actorContextImpl) SetStashCapacity(capacity int) {
+	context.stashCapacity = capacity
+}
+
+// nextUnstashed returns the next message put back by UnstashAll
+func (context *actorContextImpl) nextUnstashed() (actorMessage, bool) {
+	if len(context.unstashed) == 0 {
+		return actorMessage{}, false
+	}
+
+	actorMsg := context.unstashed[0]
+	context.unstashed = context.unstashed[1:]
+	return actorMsg, true
+}
+
+// takeStash returns the stashed and unstashed messages that were not processed, in order
+func (context *actorContextImpl) takeStash() []actorMessage {
+	messages := append(context.stash, context.unstashed...)
+	context.stash = nil
+	context.unstashed = nil
+	return messages
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrStopTimeout is returned when an actor did not stop within the given timeout
+var ErrStopTimeout = errors.New("goactors: timed out waiting for actor to stop")
+
+// StopCause tells why an actor was stopped
+type StopCause int
+
+const (
+	// StopUnknown is used when the cause is not known, for example for a Terminated
+	// sent to a watcher of an actor that had already stopped
+	StopUnknown StopCause = iota
+	// StopRequested is used when the actor was stopped through ActorContext.Stop
+	StopRequested
+	// StopParentStopped is used for children stopped along with their parent
+	StopParentStopped
+	// StopParentRestarted is used for children stopped because their parent restarted
+	StopParentRestarted
+	// StopFailed is used when the actor was stopped after it panicked
+	StopFailed
+	// StopSystemShutdown is used for all actors stopped along with the root actor
+	StopSystemShutdown
+	// StopKilled is used when the actor was stopped through ActorContext.Kill
+	StopKilled
+)
+
+func (cause StopCause) String() string {
+	switch cause {
+	case StopUnknown:
+		return "StopUnknown"
+	case StopRequested:
+		return "StopRequested"
+	case StopParentStopped:
+		return "StopParentStopped"
+	case StopParentRestarted:
+		return "StopParentRestarted"
+	case StopFailed:
+		return "StopFailed"
+	case StopSystemShutdown:
+		return "StopSystemShutdown"
+	case StopKilled:
+		return "StopKilled"
+	default:
+		return "Unknown"
+	}
+}
+
+// StopReason is handed to OnStopped, parents and watchers once an actor stopped
+type StopReason struct {
+	Cause StopCause
+
+	// The recovered panic value if the actor was stopped because it failed
+	Failure interface{}
+
+	// The recovered panic value if OnStop or OnStopped panicked. The actor is stopped
+	// all the same.
+	StopFailure interface{}
+}
+
+// StopObserver can be implemented by an actor that needs to know why it is stopping.
+// It is called instead of OnStop, after the children were stopped.
+type StopObserver interface {
+	OnStopped(context ActorContext, reason StopReason)
+}
+
+// ChildStopObserver can be implemented by an actor to learn when one of its children
+// stopped, whatever the cause. It is called on the parent's goroutine.
+type ChildStopObserver interface {
+	OnChildStopped(context ActorContext, child ActorRef, reason StopReason)
+}
+
+// cascadeReason is the reason given to the children of an actor stopping for the given reason
+func (impl *actorImpl) cascadeReason(reason StopReason) StopReason {
+	if impl.context.parent == nil || reason.Cause == StopSystemShutdown {
+		return StopReason{Cause: StopSystemShutdown}
+	}
+	return StopReason{Cause: StopParentStopped}
+}
+
+// awaitStop waits until the actor stopped or the timeout passed. A zero timeout doesn't
+// wait at all, and neither does an actor stopping itself.
+func (context *actorContextImpl) awaitStop(ref ActorRef, timeout time.Duration) error {
+	stopped := stoppedChannel(ref)
+	if timeout <= 0 || stopped == nil || ref == context.self {
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-timer.C:
+		return ErrStopTimeout
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EndOfStream is the last reply to an AskStream. It closes the stream.
+type EndOfStream struct{}
+
+// StreamCancelled is sent by a stream to the asked actor when the reader cancels it, so
+// the actor can stop producing. Its sender is the cancelled stream.
+type StreamCancelled struct{}
+
+// StreamBufferSize is how many replies a stream holds that were not read yet
+const StreamBufferSize = 1000
+
+// Stream is the receiving end of AskStream
+type Stream interface {
+	// Delivers the replies in order. Closed once the stream has ended.
+	Replies() <-chan interface{}
+
+	// Reports why the stream ended once Replies is closed: nil after EndOfStream or Cancel,
+	// the error of a Failure reply, ErrActorStopped if the actor stopped before it got the
+	// request, the actor's failure if it panicked on the request, ErrMailboxFull if more
+	// than StreamBufferSize replies were not read, or ErrAskTimeout if no reply was sent
+	// or read for DefaultAskTimeout
+	Err() error
+
+	// Ends the stream early and sends StreamCancelled to the actor. The actor is not
+	// stopped, so replies it sends until it handles StreamCancelled go to the dead
+	// letters, as do replies buffered but not read yet.
+	Cancel()
+}
+
+type streamImpl struct {
+	lock        sync.Mutex
+	buffer      []interface{}
+	ended       bool
+	err         error
+	signal      chan struct{}
+	replies     chan interface{}
+	cancelled   chan struct{}
+	cancelOnce  sync.Once
+	timeout     time.Duration
+	target      ActorRef
+	deadLetters *DeadLetters
+}
+
+// newStream starts the goroutine that hands the replies out. It returns once the stream
+// has ended and the replies are read, once the stream is cancelled, or once nothing was
+// sent or read for the timeout.
+func newStream(target ActorRef, deadLetters *DeadLetters, timeout time.Duration) *streamImpl {
+	stream := &streamImpl{
+		signal:      make(chan struct{}, 1),
+		replies:     make(chan interface{}),
+		cancelled:   make(chan struct{}),
+		timeout:     timeout,
+		target:      target,
+		deadLetters: deadLetters,
+	}
+	go stream.run()
+	return stream
+}
+
+func (stream *streamImpl) run() {
+	defer close(stream.replies)
+
+	// The timeout counts from the last reply sent or read, so a reader that walked away
+	// does not keep the goroutine around either
+	var idle <-chan time.Time
+	var timer *time.Timer
+	if stream.timeout > 0 {
+		timer = time.NewTimer(stream.timeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+	resetIdle := func() {
+		if timer != nil {
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(stream.timeout)
+		}
+	}
+
+	for {
+		stream.lock.Lock()
+		if len(stream.buffer) > 0 {
+			next := stream.buffer[0]
+			stream.lock.Unlock()
+
+			select {
+			case stream.replies <- next:
+				stream.lock.Lock()
+				stream.buffer = stream.buffer[1:]
+				stream.lock.Unlock()
+				resetIdle()
+			case <-stream.signal:
+				resetIdle()
+			case <-idle:
+				stream.expire()
+				return
+			case <-stream.cancelled:
+				stream.dropBuffer()
+				return
+			}
+			continue
+		}
+
+		ended := stream.ended
+		stream.lock.Unlock()
+		if ended {
+			return
+		}
+
+		select {
+		case <-stream.signal:
+			resetIdle()
+		case <-idle:
+			stream.expire()
+			return
+		case <-stream.cancelled:
+			return
+		}
+	}
+}
+
+// expire ends the stream once the timeout passed. A stream that already ended fails as
+// well if replies were left unread, so the reader can tell it missed some.
+func (stream *streamImpl) expire() {
+	stream.lock.Lock()
+	if !stream.ended || len(stream.buffer) > 0 {
+		stream.ended = true
+		stream.err = ErrAskTimeout
+	}
+	stream.lock.Unlock()
+
+	stream.dropBuffer()
+}
+
+// dropBuffer hands the replies nobody read to the dead letters
+func (stream *streamImpl) dropBuffer() {
+	stream.lock.Lock()
+	buffer := stream.buffer
+	stream.buffer = nil
+	stream.lock.Unlock()
+
+	for _, message := range buffer {
+		stream.deadLetters.Publish(DeadLetter{Sender: stream.target, Recipient: stream, Message: message})
+	}
+}
+
+// push adds a reply. EndOfStream and Failure end the stream. It fails with
+// ErrActorStopped once the stream has ended, or with ErrMailboxFull if the buffer is full.
+func (stream *streamImpl) push(message interface{}) error {
+	stream.lock.Lock()
+	if stream.ended {
+		stream.lock.Unlock()
+		return ErrActorStopped
+	}
+
+	switch message.(type) {
+	case EndOfStream:
+		stream.ended = true
+	case Failure:
+		stream.ended = true
+		stream.err = message.(Failure).Err
+	default:
+		if len(stream.buffer) >= StreamBufferSize {
+			stream.lock.Unlock()
+			return ErrMailboxFull
+		}
+		stream.buffer = append(stream.buffer, message)
+	}
+	stream.lock.Unlock()
+
+	stream.wake()
+	return nil
+}
+
+// end ends the stream with the error. It returns false if it had already ended.
+func (stream *streamImpl) end(err error) bool {
+	stream.lock.Lock()
+	ended := stream.ended
+	if !ended {
+		stream.ended = true
+		stream.err = err
+	}
+	stream.lock.Unlock()
+
+	stream.wake()
+	return !ended
+}
+
+func (stream *streamImpl) wake() {
+	select {
+	case stream.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (stream *streamImpl) failAsk(err error) {
+	stream.end(err)
+}
+
+func (stream *streamImpl) Replies() <-chan interface{} {
+	return stream.replies
+}
+
+func (stream *streamImpl) Err() error {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	return stream.err
+}
+
+func (stream *streamImpl) Cancel() {
+	// Only an actor that may still be replying is told
+	producing := stream.end(nil)
+	stream.cancelOnce.Do(func() { close(stream.cancelled) })
+	if producing && stream.target != nil {
+		sendAsync(stream.target, stream, StreamCancelled{})
+	}
+}
+
+func (stream *streamImpl) Path() string {
+	return "stream"
+}
+
+// Send ends the stream with ErrMailboxFull if the buffer is full, since the reader would
+// otherwise miss a reply without noticing
+func (stream *streamImpl) Send(sender ActorRef, message interface{}) {
+	err := stream.push(message)
+	if err == ErrMailboxFull {
+		stream.end(ErrMailboxFull)
+	}
+	if err != nil {
+		stream.deadLetters.Publish(DeadLetter{Sender: sender, Recipient: stream, Message: message})
+	}
+}
+
+func (stream *streamImpl) TrySend(sender ActorRef, message interface{}) error {
+	return stream.push(message)
+}
+
+func (stream *streamImpl) SendContext(ctx context.Context, sender ActorRef, message interface{}) error {
+	// Replies are buffered, so this never blocks
+	return stream.TrySend(sender, message)
+}
+
+func (stream *streamImpl) Ask(message interface{}) Future {
+	panic("Should not `Ask` on a stream")
+}
+
+func (stream *streamImpl) AskWithTimeout(message interface{}, timeout time.Duration) Future {
+	panic("Should not `Ask` on a stream")
+}
+
+func (stream *streamImpl) AskStream(message interface{}) Stream {
+	panic("Should not `Ask` on a stream")
+}
@@ -0,0 +1,196 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"time"
+)
+
+// Directive tells a failed actor how to continue after it panicked
+type Directive int
+
+const (
+	// Resume keeps the actor's state and continues with the next message
+	Resume Directive = iota
+	// Restart replaces the actor's behavior with a fresh instance from its factory
+	Restart
+	// Stop stops the actor and all of its children
+	Stop
+	// Escalate fails the parent with the same reason, deferring the decision upwards
+	Escalate
+)
+
+func (directive Directive) String() string {
+	switch directive {
+	case Resume:
+		return "Resume"
+	case Restart:
+		return "Restart"
+	case Stop:
+		return "Stop"
+	case Escalate:
+		return "Escalate"
+	default:
+		return "Unknown"
+	}
+}
+
+// SupervisorStrategy decides what happens to a child that panicked in OnStart or Receive.
+// It is always called on the parent's goroutine.
+type SupervisorStrategy interface {
+	Decide(child ActorRef, reason interface{}) Directive
+
+	// Reports whether a Restart or Stop is applied to all children instead of only the failed one
+	AppliesToAllChildren() bool
+}
+
+// Decider maps the reason of a failure (the recovered panic value) to a directive
+type Decider func(reason interface{}) Directive
+
+// Decide implements SupervisorStrategy by ignoring the child and calling the decider
+func (decider Decider) Decide(child ActorRef, reason interface{}) Directive {
+	return decider(reason)
+}
+
+// AppliesToAllChildren implements SupervisorStrategy, a decider only affects the failed child
+func (decider Decider) AppliesToAllChildren() bool {
+	return false
+}
+
+// DefaultDecider restarts the child on any failure
+func DefaultDecider(reason interface{}) Directive {
+	return Restart
+}
+
+const (
+	// DefaultMaxRestarts is how often the default strategy restarts a child within
+	// DefaultRestartWindow before stopping it
+	DefaultMaxRestarts = 10
+
+	// DefaultRestartWindow is the window in which the default strategy counts restarts
+	DefaultRestartWindow = time.Minute
+)
+
+// DefaultSupervisorStrategy returns the strategy of parents that never set their own. It
+// restarts a failed child, but stops a child that would be restarted more than
+// DefaultMaxRestarts times within DefaultRestartWindow, such as one that always fails to
+// start.
+func DefaultSupervisorStrategy() SupervisorStrategy {
+	strategy := NewOneForOneStrategy(DefaultMaxRestarts, DefaultRestartWindow, DefaultDecider)
+	strategy.overLimit = Stop
+	return strategy
+}
+
+// OneForOneStrategy applies the decider's directive to the failed child only. Once a
+// child is restarted more than maxRestarts times within the window, the failure is
+// escalated to the parent instead.
+type OneForOneStrategy struct {
+	maxRestarts int
+	within      time.Duration
+	decider     Decider
+	restarts    map[string][]time.Time
+	overLimit   Directive
+}
+
+// NewOneForOneStrategy creates a strategy that restarts, resumes or stops only the failed
+// child. A negative maxRestarts allows any number of restarts and a zero window counts
+// every restart since the strategy was created.
+func NewOneForOneStrategy(maxRestarts int, within time.Duration, decider Decider) *OneForOneStrategy {
+	if decider == nil {
+		decider = DefaultDecider
+	}
+
+	return &OneForOneStrategy{
+		maxRestarts: maxRestarts,
+		within:      within,
+		decider:     decider,
+		restarts:    make(map[string][]time.Time),
+		overLimit:   Escalate,
+	}
+}
+
+func (strategy *OneForOneStrategy) Decide(child ActorRef, reason interface{}) Directive {
+	directive := strategy.decider(reason)
+	if directive != Restart {
+		return directive
+	}
+
+	history, ok := recordRestart(strategy.restarts[child.Path()], strategy.maxRestarts, strategy.within)
+	if !ok {
+		delete(strategy.restarts, child.Path())
+		return strategy.overLimit
+	}
+
+	strategy.restarts[child.Path()] = history
+	return Restart
+}
+
+func (strategy *OneForOneStrategy) AppliesToAllChildren() bool {
+	return false
+}
+
+// AllForOneStrategy applies the decider's directive to all children of the parent, which
+// suits siblings that depend on each other. Restarts are counted for the children as a
+// group and escalated to the parent once there are more than maxRestarts within the window.
+type AllForOneStrategy struct {
+	maxRestarts int
+	within      time.Duration
+	decider     Decider
+	restarts    []time.Time
+}
+
+// NewAllForOneStrategy creates a strategy that restarts or stops all children when one of
+// them fails. The limits behave as in NewOneForOneStrategy.
+func NewAllForOneStrategy(maxRestarts int, within time.Duration, decider Decider) *AllForOneStrategy {
+	if decider == nil {
+		decider = DefaultDecider
+	}
+
+	return &AllForOneStrategy{
+		maxRestarts: maxRestarts,
+		within:      within,
+		decider:     decider,
+	}
+}
+
+func (strategy *AllForOneStrategy) Decide(child ActorRef, reason interface{}) Directive {
+	directive := strategy.decider(reason)
+	if directive != Restart {
+		return directive
+	}
+
+	history, ok := recordRestart(strategy.restarts, strategy.maxRestarts, strategy.within)
+	if !ok {
+		strategy.restarts = nil
+		return Escalate
+	}
+
+	strategy.restarts = history
+	return Restart
+}
+
+func (strategy *AllForOneStrategy) AppliesToAllChildren() bool {
+	return true
+}
+
+// recordRestart adds a restart to the history, dropping restarts that fell out of the
+// window, and reports whether the history is still within the limit
+func recordRestart(history []time.Time, maxRestarts int, within time.Duration) ([]time.Time, bool) {
+	if maxRestarts < 0 {
+		return history, true
+	}
+
+	now := time.Now()
+	if within > 0 {
+		recent := history[:0]
+		for _, restart := range history {
+			if now.Sub(restart) < within {
+				recent = append(recent, restart)
+			}
+		}
+		history = recent
+	}
+
+	history = append(history, now)
+	return history, len(history) <= maxRestarts
+}
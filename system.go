@@ -15,15 +15,20 @@ type actorMessage struct {
 
 type ActorSystem struct {
 	registry       map[string]*actorImpl
+	watchers       map[string]map[string]ActorRef
 	name           string
 	controlChannel chan interface{}
 	rootContext    ActorContext
+	deadLetters    *DeadLetters
+	scheduler      *Scheduler
 	waitGroup      sync.WaitGroup
 }
 
 type actorStopRequest struct {
 	responseChannel chan<- interface{}
 	path            string
+	ref             ActorRef
+	reason          StopReason
 }
 
 type actorCreateRequest struct {
@@ -39,14 +44,101 @@ type actorLookupRequest struct {
 	responseChannel chan<- ActorRef
 }
 
+type actorWatchRequest struct {
+	watcher ActorRef
+	watched ActorRef
+}
+
+type actorUnwatchRequest struct {
+	watcher ActorRef
+	watched ActorRef
+}
+
+// Terminated is delivered to the watchers of an actor once it stopped. If the actor
+// was already stopped when it was watched, the reason is no longer known and left empty.
+type Terminated struct {
+	Ref    ActorRef
+	Reason StopReason
+}
+
 type poisonPillMessage struct {
 	resultChannel chan<- bool
+	reason        StopReason
+	immediate     bool
+}
+
+// childStoppedMessage tells a parent that one of its children stopped
+type childStoppedMessage struct {
+	ref    ActorRef
+	reason StopReason
+}
+
+// isSystemMessage reports whether the message is only meant for the actor system itself
+func isSystemMessage(message interface{}) bool {
+	switch message.(type) {
+	case poisonPillMessage, actorFailedMessage, supervisorDirectiveMessage, childStoppedMessage:
+		return true
+	default:
+		return false
+	}
+}
+
+// unwrapMessage returns the message an internal wrapper carries for the actor, which is
+// what a dead letter reports
+func unwrapMessage(message interface{}) interface{} {
+	switch message.(type) {
+	case pipedResultMessage:
+		piped := message.(pipedResultMessage)
+		return pipedMessage(piped.value, piped.err)
+	case continuationMessage:
+		continuation := message.(continuationMessage)
+		return pipedMessage(continuation.value, continuation.err)
+	case timerMessage:
+		return message.(timerMessage).message
+	default:
+		return message
+	}
+}
+
+// pipedResultMessage carries a future's result piped to an actor. It is turned into the
+// actual message on the actor's own goroutine.
+type pipedResultMessage struct {
+	mapFn func(value interface{}, err error) interface{}
+	value interface{}
+	err   error
+}
+
+// timerMessage is sent by an actor's timer to the actor itself
+type timerMessage struct {
+	key        interface{}
+	generation int
+	message    interface{}
+}
+
+// continuationMessage runs the continuation of an Await on the actor's own goroutine
+type continuationMessage struct {
+	fn          func(context ActorContext, result interface{}, err error)
+	value       interface{}
+	err         error
+	incarnation int
+}
+
+// actorFailedMessage is sent by a child to its parent after the child panicked
+type actorFailedMessage struct {
+	reason interface{}
+}
+
+// supervisorDirectiveMessage is the parent's answer to an actorFailedMessage. Siblings
+// restarted along with a failed child get the child's failure as the reason.
+type supervisorDirectiveMessage struct {
+	directive Directive
+	reason    interface{}
 }
 
 func (system *ActorSystem) lookupRefBackend(name string) ActorRef {
 	impl, ok := system.registry[name]
 	if ok {
-		return impl.context.self
+		return impl.ref
 	}
 	return nil
 }
@@ -55,6 +147,8 @@ func (system *ActorSystem) start() ActorContext {
 	rootImpl := newActor(
 		path.Join("/", system.name),
 		system.controlChannel,
+		system.deadLetters,
+		system.scheduler,
 		actorCreateRequest{
 			parent: nil,
 			factoryFunction: func() Actor {
@@ -81,13 +175,23 @@ func (system *ActorSystem) start() ActorContext {
 				break
 			case actorStopRequest:
 				var request = msg.(actorStopRequest)
+				system.notifyParent(request)
 				delete(system.registry, request.path)
+				system.notifyWatchers(request.path, Terminated{Ref: request.ref, Reason: request.reason})
 				request.responseChannel <- true
 
 				if request.path == rootRef.Path() {
 					break loop
 				}
 				break
+			case actorWatchRequest:
+				var request = msg.(actorWatchRequest)
+				system.addWatcher(request.watcher, request.watched)
+				break
+			case actorUnwatchRequest:
+				var request = msg.(actorUnwatchRequest)
+				delete(system.watchers[request.watched.Path()], request.watcher.Path())
+				break
 			case actorCreateRequest:
 				var request = msg.(actorCreateRequest)
 				var name = request.name
@@ -103,7 +207,7 @@ func (system *ActorSystem) start() ActorContext {
 					// Actor already exists - send back nil
 					request.responseChannel <- nil
 				} else {
-					actorImpl := newActor(name, system.controlChannel, request)
+					actorImpl := newActor(name, system.controlChannel, system.deadLetters, system.scheduler, request)
 					system.registry[name] = actorImpl
 				}
 				break
@@ -112,6 +216,7 @@ func (system *ActorSystem) start() ActorContext {
 			}
 		}
 
+		system.scheduler.stop()
 		system.waitGroup.Done()
 		fmt.Println("Shutting down actor system")
 	})()
@@ -119,6 +224,47 @@ func (system *ActorSystem) start() ActorContext {
 	return context
 }
 
+func (system *ActorSystem) addWatcher(watcher ActorRef, watched ActorRef) {
+	if _, ok := system.registry[watched.Path()]; !ok {
+		// Already stopped, so the watcher is notified right away
+		go watcher.Send(watched, Terminated{Ref: watched})
+		return
+	}
+
+	watchers, ok := system.watchers[watched.Path()]
+	if !ok {
+		watchers = make(map[string]ActorRef)
+		system.watchers[watched.Path()] = watchers
+	}
+	watchers[watcher.Path()] = watcher
+}
+
+func (system *ActorSystem) notifyParent(request actorStopRequest) {
+	impl, ok := system.registry[request.path]
+	if !ok || impl.context.parent == nil {
+		return
+	}
+
+	if _, ok := system.registry[impl.context.parent.Path()]; !ok {
+		return
+	}
+
+	// Sent asynchronously, since the parent may be waiting on this actor to stop
+	go impl.context.parent.Send(request.ref, childStoppedMessage{ref: request.ref, reason: request.reason})
+}
+
+func (system *ActorSystem) notifyWatchers(path string, terminated Terminated) {
+	for watcherPath, watcher := range system.watchers[path] {
+		if _, ok := system.registry[watcherPath]; !ok {
+			continue
+		}
+
+		// Sent asynchronously, since the watcher may be waiting on this actor to stop
+		go watcher.Send(terminated.Ref, terminated)
+	}
+	delete(system.watchers, path)
+}
+
 type rootActor struct {
 	DefaultActor
 }
@@ -131,6 +277,16 @@ func (system *ActorSystem) Context() ActorContext {
 	return system.rootContext
 }
 
+// DeadLetters returns the sink for messages that could not be delivered
+func (system *ActorSystem) DeadLetters() *DeadLetters {
+	return system.deadLetters
+}
+
+// Scheduler returns the scheduler for sending messages after a delay
+func (system *ActorSystem) Scheduler() *Scheduler {
+	return system.scheduler
+}
+
 func (system *ActorSystem) Wait() {
 	system.waitGroup.Wait()
 }
@@ -139,6 +295,9 @@ func NewSystem(name string) *ActorSystem {
 	system := new(ActorSystem)
 	system.name = name
 	system.registry = make(map[string]*actorImpl)
+	system.watchers = make(map[string]map[string]ActorRef)
+	system.deadLetters = newDeadLetters()
+	system.scheduler = newScheduler()
 	system.controlChannel = make(chan interface{})
 	system.waitGroup = sync.WaitGroup{}
 
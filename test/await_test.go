@@ -0,0 +1,99 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+// reentrantActor asks its peer, which asks back before answering
+type reentrantActor struct {
+	goactors.DefaultActor
+	peer string
+}
+
+func (a *reentrantActor) Receive(context goactors.ActorContext, message interface{}) {
+	switch message {
+	case "outer":
+		context.Await(context.FindActor(a.peer).Ask("middle"), func(context goactors.ActorContext, result interface{}, err error) {
+			context.SenderRef().Send(context.SelfRef(), fmt.Sprintf("outer(%v)", result))
+		})
+	case "middle":
+		context.Await(context.FindActor(a.peer).Ask("inner"), func(context goactors.ActorContext, result interface{}, err error) {
+			context.SenderRef().Send(context.SelfRef(), fmt.Sprintf("middle(%v)", result))
+		})
+	case "inner":
+		context.SenderRef().Send(context.SelfRef(), "inner")
+	}
+}
+
+func TestAwaitAllowsAskingBack(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	a := context.CreateActorFromFunc(func() goactors.Actor { return &reentrantActor{peer: "/test/b"} }, "a")
+	context.CreateActorFromFunc(func() goactors.Actor { return &reentrantActor{peer: "/test/a"} }, "b")
+
+	if result, err := a.Ask("outer").GetResultTimeout(time.Second); result != "outer(middle(inner))" || err != nil {
+		t.Errorf("Expected %v, received %v, %v", "outer(middle(inner))", result, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type awaitingActor struct {
+	goactors.DefaultActor
+	promise *goactors.Promise
+	events  chan<- string
+}
+
+func (a *awaitingActor) Receive(context goactors.ActorContext, message interface{}) {
+	switch message {
+	case "await":
+		context.Await(a.promise.Future(), func(context goactors.ActorContext, result interface{}, err error) {
+			a.events <- fmt.Sprintf("continued %v", result)
+		})
+		a.events <- "awaiting"
+	case "panic":
+		panic("boom")
+	}
+}
+
+func TestAwaitContinuationIsDroppedOnRestart(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+	first := goactors.NewPromise()
+	second := goactors.NewPromise()
+
+	promises := []*goactors.Promise{first, second}
+	ref := context.CreateActorFromFunc(func() goactors.Actor {
+		promise := promises[0]
+		promises = promises[1:]
+		return &awaitingActor{promise: promise, events: events}
+	}, "awaiting")
+
+	ref.Send(nil, "await")
+	expectEvent(t, events, "awaiting")
+	ref.Send(nil, "panic")
+	ref.Send(nil, "await")
+	expectEvent(t, events, "awaiting")
+
+	// Only the restarted behavior's continuation runs
+	first.Complete(1)
+	second.Complete(2)
+	expectEvent(t, events, "continued 2")
+	select {
+	case event := <-events:
+		t.Errorf("Expected no more events, received %q", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
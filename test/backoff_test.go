@@ -0,0 +1,172 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+func TestBackoffSupervisorBuffersWhileChildIsDown(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	factory, err := goactors.NewBackoffSupervisor(func() goactors.Actor {
+		return &flakyActor{events: events}
+	}, "child", goactors.BackoffOptions{
+		MinBackoff: 50 * time.Millisecond,
+		MaxBackoff: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Expected valid options, received %v", err)
+	}
+	supervisor := context.CreateActorFromFunc(factory, "worker")
+	expectEvent(t, events, "start /test/worker/child")
+
+	supervisor.Send(nil, "panic")
+	expectEvent(t, events, "stop")
+	stoppedAt := time.Now()
+
+	supervisor.Send(nil, "ping")
+	future := supervisor.Ask("count")
+
+	expectEvent(t, events, "start /test/worker/child")
+	if delay := time.Since(stoppedAt); delay < 40*time.Millisecond {
+		t.Errorf("Expected the child to restart after the backoff, restarted after %v", delay)
+	}
+
+	// The buffered ping was delivered to the new instance before the count
+	if count := future.GetResult(); count != 2 {
+		t.Errorf("Expected count %d, received %v", 2, count)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestBackoffSupervisorDropsWhileChildIsDown(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	factory, err := goactors.NewBackoffSupervisor(func() goactors.Actor {
+		return &flakyActor{events: events}
+	}, "child", goactors.BackoffOptions{
+		MinBackoff:    50 * time.Millisecond,
+		MaxBackoff:    time.Second,
+		DropWhileDown: true,
+	})
+	if err != nil {
+		t.Fatalf("Expected valid options, received %v", err)
+	}
+	supervisor := context.CreateActorFromFunc(factory, "worker")
+	expectEvent(t, events, "start /test/worker/child")
+
+	supervisor.Send(nil, "panic")
+	expectEvent(t, events, "stop")
+	supervisor.Send(nil, "ping")
+	expectEvent(t, events, "start /test/worker/child")
+
+	if count := supervisor.Ask("count").GetResult(); count != 1 {
+		t.Errorf("Expected count %d, received %v", 1, count)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestBackoffSupervisorDeadLettersBufferOverflow(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+
+	factory, err := goactors.NewBackoffSupervisor(func() goactors.Actor {
+		return &flakyActor{events: events}
+	}, "child", goactors.BackoffOptions{
+		MinBackoff:  50 * time.Millisecond,
+		MaxBackoff:  time.Second,
+		MaxBuffered: 1,
+	})
+	if err != nil {
+		t.Fatalf("Expected valid options, received %v", err)
+	}
+	supervisor := context.CreateActorFromFunc(factory, "worker")
+	expectEvent(t, events, "start /test/worker/child")
+
+	supervisor.Send(nil, "panic")
+	expectEvent(t, events, "stop")
+	supervisor.Send(nil, "ping")
+	supervisor.Send(nil, "overflow")
+	expectDeadLetter(t, letters, "/test/worker", "overflow")
+	expectEvent(t, events, "start /test/worker/child")
+
+	if count := supervisor.Ask("count").GetResult(); count != 2 {
+		t.Errorf("Expected count %d, received %v", 2, count)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestBackoffSupervisorRejectsInvalidOptions(t *testing.T) {
+	invalid := []goactors.BackoffOptions{
+		{MaxBackoff: time.Second},
+		{MinBackoff: time.Second},
+		{MinBackoff: time.Second, MaxBackoff: time.Millisecond},
+		{MinBackoff: time.Millisecond, MaxBackoff: time.Second, RandomFactor: -1},
+	}
+
+	for _, options := range invalid {
+		if _, err := goactors.NewBackoffSupervisor(func() goactors.Actor { return &goactors.DefaultActor{} }, "child", options); err == nil {
+			t.Errorf("Expected options %+v to be rejected", options)
+		}
+	}
+}
+
+func TestBackoffSupervisorDoublesCapsAndResetsDelay(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	factory, err := goactors.NewBackoffSupervisor(func() goactors.Actor {
+		return &flakyActor{events: events}
+	}, "child", goactors.BackoffOptions{
+		MinBackoff: 40 * time.Millisecond,
+		MaxBackoff: 160 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Expected valid options, received %v", err)
+	}
+	supervisor := context.CreateActorFromFunc(factory, "worker")
+	expectEvent(t, events, "start /test/worker/child")
+
+	failAndMeasure := func(expected time.Duration) {
+		t.Helper()
+		supervisor.Send(nil, "panic")
+		expectEvent(t, events, "stop")
+		stoppedAt := time.Now()
+		expectEvent(t, events, "start /test/worker/child")
+
+		if delay := time.Since(stoppedAt); delay < expected*8/10 || delay > expected+30*time.Millisecond {
+			t.Errorf("Expected a restart after about %v, restarted after %v", expected, delay)
+		}
+	}
+
+	// Failing right after every restart doubles the delay up to MaxBackoff
+	failAndMeasure(40 * time.Millisecond)
+	failAndMeasure(80 * time.Millisecond)
+	failAndMeasure(160 * time.Millisecond)
+	failAndMeasure(160 * time.Millisecond)
+
+	// A child that stayed up for longer than MaxBackoff starts over at MinBackoff
+	time.Sleep(200 * time.Millisecond)
+	failAndMeasure(40 * time.Millisecond)
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+// sessionActor goes from connecting to connected, with a stacked busy phase
+type sessionActor struct {
+	goactors.DefaultActor
+}
+
+func (a *sessionActor) Receive(context goactors.ActorContext, message interface{}) {
+	switch message {
+	case "connect":
+		context.Become(a.connected)
+	case "panic":
+		panic("boom")
+	default:
+		context.SenderRef().Send(context.SelfRef(), "connecting")
+	}
+}
+
+func (a *sessionActor) connected(context goactors.ActorContext, message interface{}) {
+	switch message {
+	case "busy":
+		context.BecomeStacked(a.busy)
+	case "disconnect":
+		context.Unbecome()
+	case "panic":
+		panic("boom")
+	default:
+		context.SenderRef().Send(context.SelfRef(), "connected")
+	}
+}
+
+func (a *sessionActor) busy(context goactors.ActorContext, message interface{}) {
+	switch message {
+	case "done":
+		context.Unbecome()
+	default:
+		context.SenderRef().Send(context.SelfRef(), "busy")
+	}
+}
+
+func expectPhase(t *testing.T, ref goactors.ActorRef, phase string) {
+	t.Helper()
+	if result, err := goactors.Ask[string, string](ref, "state", time.Second); result != phase || err != nil {
+		t.Errorf("Expected %v, received %v, %v", phase, result, err)
+	}
+}
+
+func TestBecomeAndUnbecome(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	session := context.CreateActorFromFunc(func() goactors.Actor { return &sessionActor{} }, "session")
+	expectPhase(t, session, "connecting")
+
+	session.Send(nil, "connect")
+	expectPhase(t, session, "connected")
+
+	session.Send(nil, "busy")
+	expectPhase(t, session, "busy")
+
+	session.Send(nil, "done")
+	expectPhase(t, session, "connected")
+
+	session.Send(nil, "disconnect")
+	expectPhase(t, session, "connecting")
+
+	// A restart starts over with Receive
+	session.Send(nil, "connect")
+	session.Send(nil, "panic")
+	expectPhase(t, session, "connecting")
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
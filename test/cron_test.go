@@ -0,0 +1,134 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+func TestParseCronNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("No time zone data: %v", err)
+	}
+
+	cases := []struct {
+		expression string
+		after      time.Time
+		expected   time.Time
+	}{
+		{"0 */5 * * * *", time.Date(2024, 6, 1, 10, 2, 30, 0, time.UTC), time.Date(2024, 6, 1, 10, 5, 0, 0, time.UTC)},
+		{"50/1 * * * * *", time.Date(2024, 6, 1, 10, 0, 50, 0, time.UTC), time.Date(2024, 6, 1, 10, 0, 51, 0, time.UTC)},
+		{"30 0 9 * * MON-FRI", time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 6, 3, 9, 0, 30, 0, time.UTC)},
+		{"0 0 0 29 2 *", time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 0 */1 * MON", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 0 13 * FRI", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 7, 0, 0, 0, 0, time.UTC)},
+		{"15 * * * *", time.Date(2024, 6, 1, 10, 20, 0, 0, time.UTC), time.Date(2024, 6, 1, 11, 15, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 12, 31, 10, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 12 1,15 JAN,JUL *", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)},
+		{"CRON_TZ=America/New_York 0 0 9 * * *", time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 9, 0, 0, 0, newYork)},
+	}
+
+	for _, c := range cases {
+		// Pinned to UTC, since expressions without a time zone are in time.Local
+		expression := c.expression
+		if !strings.HasPrefix(expression, "CRON_TZ=") {
+			expression = "CRON_TZ=UTC " + expression
+		}
+
+		schedule, err := goactors.ParseCron(expression)
+		if err != nil {
+			t.Errorf("Expected %q to parse, received %v", expression, err)
+			continue
+		}
+
+		if next := schedule.Next(c.after); !next.Equal(c.expected) {
+			t.Errorf("Expected %q after %v to be %v, received %v", expression, c.after, c.expected, next)
+		}
+	}
+}
+
+func TestParseCronNextAcrossDaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("No time zone data: %v", err)
+	}
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Skipf("No time zone data: %v", err)
+	}
+
+	// 1:30 happens twice when New York falls back
+	fallBack := time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		expression string
+		after      time.Time
+		expected   time.Time
+	}{
+		// 2:30 does not exist on the day New York springs forward
+		{"CRON_TZ=America/New_York 0 30 2 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), time.Date(2024, 3, 11, 2, 30, 0, 0, newYork)},
+		{"CRON_TZ=America/New_York 0 30 1 * * *", time.Date(2024, 11, 3, 0, 0, 0, 0, newYork), fallBack},
+		{"CRON_TZ=America/New_York 0 30 1 * * *", fallBack, time.Date(2024, 11, 4, 1, 30, 0, 0, newYork)},
+		// Santiago springs forward at midnight, so September 8 starts at 1:00
+		{"CRON_TZ=America/Santiago 0 0 12 8 * *", time.Date(2024, 9, 7, 13, 0, 0, 0, santiago), time.Date(2024, 9, 8, 12, 0, 0, 0, santiago)},
+		{"CRON_TZ=America/Santiago 0 0 * 8 9 *", time.Date(2024, 9, 7, 13, 0, 0, 0, santiago), time.Date(2024, 9, 8, 1, 0, 0, 0, santiago)},
+	}
+
+	for _, c := range cases {
+		schedule, err := goactors.ParseCron(c.expression)
+		if err != nil {
+			t.Fatalf("Expected %q to parse, received %v", c.expression, err)
+		}
+
+		if next := schedule.Next(c.after); !next.Equal(c.expected) {
+			t.Errorf("Expected %q after %v to be %v, received %v", c.expression, c.after, c.expected, next)
+		}
+	}
+}
+
+func TestParseCronRejectsInvalidExpressions(t *testing.T) {
+	for _, expression := range []string{"61 * * * * *", "* * *", "*/0 * * * * *", "5-1 * * * * *", "CRON_TZ=Nowhere/City * * * * * *", "0 0 0 * FOO *"} {
+		if _, err := goactors.ParseCron(expression); err == nil {
+			t.Errorf("Expected %q to be rejected", expression)
+		}
+	}
+}
+
+func TestScheduleCron(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 100)
+
+	if _, err := system.ScheduleCron("0 0 0 30 2 *", context.SelfRef(), "never"); err == nil {
+		t.Errorf("Expected a schedule that never fires to be rejected")
+	}
+	if _, err := system.Scheduler().ScheduleCron("0 0 0 30 2 *", context.SelfRef(), "never", goactors.CronOptions{Location: time.UTC}); err == nil {
+		t.Errorf("Expected a schedule that never fires to be rejected with options too")
+	}
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &timerActor{events: events} }, "cron")
+	task, err := system.ScheduleCron("* * * * * *", ref, "tick")
+	if err != nil {
+		t.Fatalf("Expected the schedule to be accepted, received %v", err)
+	}
+
+	start := time.Now()
+	expectEvent(t, events, "tick")
+	select {
+	case <-events:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the second tick")
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("Expected the ticks to be a second apart, received two within %v", elapsed)
+	}
+
+	task.Cancel()
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
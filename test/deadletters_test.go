@@ -0,0 +1,106 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+func subscribeDeadLetters(system *goactors.ActorSystem) (<-chan goactors.DeadLetter, func()) {
+	letters := make(chan goactors.DeadLetter, 100)
+	unsubscribe := system.DeadLetters().Subscribe(func(letter goactors.DeadLetter) {
+		letters <- letter
+	})
+	return letters, unsubscribe
+}
+
+func expectDeadLetter(t *testing.T, letters <-chan goactors.DeadLetter, recipient string, message interface{}) {
+	t.Helper()
+	select {
+	case letter := <-letters:
+		if letter.Recipient.Path() != recipient {
+			t.Errorf("Expected dead letter to %s, received one to %s", recipient, letter.Recipient.Path())
+		}
+		if letter.Message != message {
+			t.Errorf("Expected dead letter %v, received %v", message, letter.Message)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for dead letter %v", message)
+	}
+}
+
+func TestSendToStoppedActorIsDeadLettered(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &goactors.DefaultActor{} }, "target")
+	if err := context.StopGracefully(ref, time.Second); err != nil {
+		t.Fatalf("Expected actor to stop, received %v", err)
+	}
+
+	// More messages than the mailbox holds, which used to block forever
+	for i := 0; i < 20; i++ {
+		ref.Send(context.SelfRef(), i)
+	}
+
+	for i := 0; i < 20; i++ {
+		expectDeadLetter(t, letters, "/test/target", i)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestKilledMailboxIsDeadLettered(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+	processed := make(chan interface{}, 100)
+
+	ref := context.CreateProxyActorFromFunc(func() goactors.Actor {
+		return &slowActor{processed: processed, delay: 10 * time.Millisecond}
+	}, "slow")
+	for i := 0; i < 30; i++ {
+		ref.Send(nil, i)
+	}
+
+	if err := context.Kill(ref, time.Second); err != nil {
+		t.Fatalf("Expected actor to stop, received %v", err)
+	}
+
+	// Every message was either processed or handed to the dead letters
+	if total := len(processed) + len(letters); total != 30 {
+		t.Errorf("Expected %d processed or dead lettered messages, received %d", 30, total)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestSecondReplyToFutureIsDeadLettered(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &echoActorDoubleSender{} }, "echo")
+	ref.Ask("ping").GetResult()
+	expectDeadLetter(t, letters, "future", "ping")
+
+	// No more letters after unsubscribing
+	unsubscribe()
+	ref.Ask("ping").GetResult()
+	select {
+	case letter := <-letters:
+		t.Errorf("Expected no dead letters after unsubscribing, received %v", letter.Message)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+type watcherActor struct {
+	goactors.DefaultActor
+	target     goactors.ActorRef
+	terminated chan<- goactors.Terminated
+}
+
+func (a *watcherActor) OnStart(context goactors.ActorContext) {
+	context.Watch(a.target)
+}
+
+func (a *watcherActor) Receive(context goactors.ActorContext, message interface{}) {
+	switch message.(type) {
+	case goactors.Terminated:
+		a.terminated <- message.(goactors.Terminated)
+	case string:
+		context.Unwatch(a.target)
+		context.SenderRef().Send(context.SelfRef(), message)
+	}
+}
+
+func createWatcher(context goactors.ActorContext, target goactors.ActorRef) <-chan goactors.Terminated {
+	terminated := make(chan goactors.Terminated, 1)
+	context.CreateActorFromFunc(func() goactors.Actor {
+		return &watcherActor{target: target, terminated: terminated}
+	}, "watcher")
+	return terminated
+}
+
+func expectTerminated(t *testing.T, terminated <-chan goactors.Terminated, path string, reason goactors.StopReason) {
+	t.Helper()
+	select {
+	case msg := <-terminated:
+		if msg.Ref.Path() != path {
+			t.Errorf("Expected termination of %s, received %s", path, msg.Ref.Path())
+		}
+		if msg.Reason != reason {
+			t.Errorf("Expected reason %v, received %v", reason, msg.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for termination of %s", path)
+	}
+}
+
+func TestWatchNotifiesWhenActorStops(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	target := context.CreateActorFromFunc(func() goactors.Actor { return &goactors.DefaultActor{} }, "target")
+	terminated := createWatcher(context, target)
+
+	context.Stop(target)
+	expectTerminated(t, terminated, "/test/target", goactors.StopReason{Cause: goactors.StopRequested})
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestWatchNotifiesWhenActorFails(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	context.SetSupervisorStrategy(goactors.Decider(func(reason interface{}) goactors.Directive {
+		return goactors.Stop
+	}))
+	events := make(chan string, 10)
+
+	target := context.CreateActorFromFunc(func() goactors.Actor { return &flakyActor{events: events} }, "target")
+	terminated := createWatcher(context, target)
+
+	target.Send(nil, "panic")
+	expectTerminated(t, terminated, "/test/target", goactors.StopReason{Cause: goactors.StopFailed, Failure: "bad message"})
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestWatchNotifiesWhenActorAlreadyStopped(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	target := context.CreateActorFromFunc(func() goactors.Actor { return &goactors.DefaultActor{} }, "target")
+	context.Stop(target)
+	for context.FindActor("/test/target") != nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	terminated := createWatcher(context, target)
+	expectTerminated(t, terminated, "/test/target", goactors.StopReason{Cause: goactors.StopUnknown})
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestUnwatchStopsNotifications(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	target := context.CreateActorFromFunc(func() goactors.Actor { return &goactors.DefaultActor{} }, "target")
+	terminated := createWatcher(context, target)
+
+	context.FindActor("/test/watcher").Ask("unwatch").GetResult()
+	context.Stop(target)
+
+	select {
+	case msg := <-terminated:
+		t.Errorf("Expected no notification after unwatch, received %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestRootContextWatchFromOutside(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	// The root handles the Terminated of one target while the next one is watched
+	for i := 0; i < 20; i++ {
+		target := context.CreateActorFromFunc(func() goactors.Actor { return &goactors.DefaultActor{} }, "target")
+		context.Watch(target)
+		if err := context.StopGracefully(target, time.Second); err != nil {
+			t.Fatalf("Expected the target to stop, received %v", err)
+		}
+		context.Unwatch(target)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type panickingStopActor struct {
+	goactors.DefaultActor
+}
+
+func (a *panickingStopActor) OnStop() {
+	panic("could not stop cleanly")
+}
+
+func TestWatchReportsPanicWhileStopping(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	target := context.CreateActorFromFunc(func() goactors.Actor { return &panickingStopActor{} }, "target")
+	terminated := createWatcher(context, target)
+
+	context.Stop(target)
+	expectTerminated(t, terminated, "/test/target", goactors.StopReason{Cause: goactors.StopRequested, StopFailure: "could not stop cleanly"})
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+const (
+	locked   goactors.FSMState = "locked"
+	unlocked goactors.FSMState = "unlocked"
+)
+
+// newTurnstile counts coins and locks again on its own after a while
+func newTurnstile(events chan<- string) goactors.Actor {
+	fsm := goactors.NewFSMActor(locked, 0)
+	fsm.When(locked, 0, func(context goactors.ActorContext, event goactors.FSMEvent) *goactors.FSMTransition {
+		switch event.Message {
+		case "coin":
+			return goactors.Goto(unlocked).Using(event.Data.(int) + 1)
+		case "push":
+			context.SenderRef().Send(context.SelfRef(), "locked")
+			return goactors.Stay()
+		case "lock":
+			return goactors.Goto(locked)
+		case "jam":
+			return goactors.Goto("jammed")
+		}
+		return nil
+	})
+	fsm.When(unlocked, 50*time.Millisecond, func(context goactors.ActorContext, event goactors.FSMEvent) *goactors.FSMTransition {
+		switch event.Message {
+		case "push":
+			context.SenderRef().Send(context.SelfRef(), "through")
+			return goactors.Goto(locked)
+		case "coin":
+			return goactors.Stay().Using(event.Data.(int) + 1)
+		case goactors.StateTimeout{}:
+			return goactors.Goto(locked)
+		}
+		return nil
+	})
+	fsm.WhenUnhandled(func(context goactors.ActorContext, event goactors.FSMEvent) *goactors.FSMTransition {
+		if event.Message == "coins" {
+			context.SenderRef().Send(context.SelfRef(), event.Data)
+			return goactors.Stay()
+		}
+		return nil
+	})
+	fsm.OnTransition(func(context goactors.ActorContext, from goactors.FSMState, to goactors.FSMState) {
+		events <- fmt.Sprintf("%s -> %s", from, to)
+	})
+	return fsm
+}
+
+func expectPush(t *testing.T, turnstile goactors.ActorRef, expected string) {
+	t.Helper()
+	if result, err := goactors.Ask[string, string](turnstile, "push", time.Second); result != expected || err != nil {
+		t.Errorf("Expected %v, received %v, %v", expected, result, err)
+	}
+}
+
+func TestFSMActorTransitions(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	turnstile := context.CreateActorFromFunc(func() goactors.Actor { return newTurnstile(events) }, "turnstile")
+
+	expectPush(t, turnstile, "locked")
+	turnstile.Send(nil, "coin")
+	expectEvent(t, events, "locked -> unlocked")
+	turnstile.Send(nil, "coin")
+	expectPush(t, turnstile, "through")
+	expectEvent(t, events, "unlocked -> locked")
+
+	if coins, err := goactors.Ask[string, int](turnstile, "coins", time.Second); coins != 2 || err != nil {
+		t.Errorf("Expected %v coins, received %v, %v", 2, coins, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestFSMActorStateTimeout(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	turnstile := context.CreateActorFromFunc(func() goactors.Actor { return newTurnstile(events) }, "turnstile")
+	turnstile.Send(nil, "coin")
+	expectEvent(t, events, "locked -> unlocked")
+	expectEvent(t, events, "unlocked -> locked")
+	expectPush(t, turnstile, "locked")
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestFSMActorGotoCurrentOrUndefinedState(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	turnstile := context.CreateActorFromFunc(func() goactors.Actor { return newTurnstile(events) }, "turnstile")
+
+	// Going to the current state is no transition
+	turnstile.Send(nil, "lock")
+	expectPush(t, turnstile, "locked")
+	expectNoEvent(t, events, 20*time.Millisecond)
+
+	// An undefined state is a failure the asker learns about right away
+	if _, err := turnstile.AskWithTimeout("jam", time.Second).Result(); err == nil || err == goactors.ErrAskTimeout {
+		t.Errorf("Expected the undefined state to fail the ask, received %v", err)
+	}
+
+	// Unhandled messages fail their ask without waiting for the timeout
+	if _, err := turnstile.AskWithTimeout("dance", time.Second).Result(); err != goactors.ErrUnhandledMessage {
+		t.Errorf("Expected %v, received %v", goactors.ErrUnhandledMessage, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+// undefinedStartFSM starts in a state it never set up
+type undefinedStartFSM struct {
+	*goactors.FSMActor
+	events chan<- string
+}
+
+func (a *undefinedStartFSM) OnStopped(context goactors.ActorContext, reason goactors.StopReason) {
+	a.events <- fmt.Sprintf("%v %v", reason.Cause, reason.Failure)
+}
+
+func TestFSMActorUndefinedInitialState(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	context.CreateActorFromFunc(func() goactors.Actor {
+		fsm := goactors.NewFSMActor("missing", nil)
+		fsm.When(locked, 0, func(context goactors.ActorContext, event goactors.FSMEvent) *goactors.FSMTransition {
+			return goactors.Stay()
+		})
+		return &undefinedStartFSM{FSMActor: fsm, events: events}
+	}, "fsm")
+
+	// The FSM stops with an error instead of being restarted by its supervisor
+	expectEvent(t, events, `StopFailed goactors: FSM initial state "missing" is not defined`)
+	expectNoEvent(t, events, 50*time.Millisecond)
+	if ref := context.FindActor("/test/fsm"); ref != nil {
+		t.Errorf("Expected the FSM to be stopped, found %v", ref.Path())
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
@@ -3,8 +3,11 @@
 package test
 
 import (
+	ctx "context"
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/cgrunewald/goactors"
 )
@@ -40,9 +43,10 @@ func TestActorAsk(t *testing.T) {
 		t.Errorf("Expected %s, received %s", "ping", "resultString")
 	}
 
+	// The result is kept for later reads
 	result = future.GetResult()
-	if result != nil {
-		t.Errorf("Expected second call to GetResult to be nil")
+	if result != "ping" {
+		t.Errorf("Expected second call to GetResult to return %s, received %v", "ping", result)
 	}
 }
 
@@ -84,3 +88,275 @@ func TestActorAskForward(t *testing.T) {
 
 	wg.Wait()
 }
+
+type silentActor struct {
+	goactors.DefaultActor
+}
+
+func TestAskTimesOut(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	silent := context.CreateActorFromFunc(func() goactors.Actor { return &silentActor{} }, "silent")
+	future := silent.AskWithTimeout("ping", 10*time.Millisecond)
+
+	if _, err := future.GetResultTimeout(time.Second); err != goactors.ErrAskTimeout {
+		t.Errorf("Expected %v, received %v", goactors.ErrAskTimeout, err)
+	}
+
+	// A reply after the timeout no longer completes the future
+	if err := future.(goactors.ActorRef).TrySend(nil, "late"); err != goactors.ErrActorStopped {
+		t.Errorf("Expected %v, received %v", goactors.ErrActorStopped, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestGetResultTimeoutAndAwait(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	silent := context.CreateActorFromFunc(func() goactors.Actor { return &silentActor{} }, "silent")
+	if _, err := silent.Ask("ping").GetResultTimeout(10 * time.Millisecond); err != goactors.ErrAskTimeout {
+		t.Errorf("Expected %v, received %v", goactors.ErrAskTimeout, err)
+	}
+
+	cancelled, cancel := ctx.WithCancel(ctx.Background())
+	cancel()
+	if _, err := silent.Ask("ping").Await(cancelled); err != ctx.Canceled {
+		t.Errorf("Expected %v, received %v", ctx.Canceled, err)
+	}
+
+	echo := context.CreateActorFromFunc(func() goactors.Actor { return &echoActor{} }, "echo")
+	if result, err := echo.Ask("ping").Await(ctx.Background()); result != "ping" || err != nil {
+		t.Errorf("Expected %v, received %v, %v", "ping", result, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type doublingActor struct {
+	goactors.DefaultActor
+}
+
+func (a *doublingActor) Receive(context goactors.ActorContext, message interface{}) {
+	context.SenderRef().Send(context.SelfRef(), message.(int)*2)
+}
+
+func TestFutureMapFlatMapAndRecover(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	doubler := context.CreateActorFromFunc(func() goactors.Actor { return &doublingActor{} }, "doubler")
+	future := doubler.Ask(1).
+		Map(func(value interface{}) interface{} { return value.(int) + 1 }).
+		Then(func(value interface{}) goactors.Future { return doubler.Ask(value) })
+	if result, err := future.GetResultTimeout(time.Second); result != 6 || err != nil {
+		t.Errorf("Expected %v, received %v, %v", 6, result, err)
+	}
+
+	silent := context.CreateActorFromFunc(func() goactors.Actor { return &silentActor{} }, "silent")
+	recovered := silent.AskWithTimeout("ping", 10*time.Millisecond).
+		Map(func(value interface{}) interface{} {
+			t.Error("Expected Map to be skipped for a failed future")
+			return value
+		}).
+		Recover(func(err error) interface{} { return err })
+	if result, err := recovered.GetResultTimeout(time.Second); result != goactors.ErrAskTimeout || err != nil {
+		t.Errorf("Expected %v, received %v, %v", goactors.ErrAskTimeout, result, err)
+	}
+
+	panicking := doubler.Ask(1).Map(func(value interface{}) interface{} { panic("boom") })
+	if _, err := panicking.GetResultTimeout(time.Second); err == nil {
+		t.Errorf("Expected a panicking Map to fail the future")
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestFutureSequenceAndFirstCompleted(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	doubler := context.CreateActorFromFunc(func() goactors.Actor { return &doublingActor{} }, "doubler")
+	futures := []goactors.Future{}
+	for i := 0; i < 10; i++ {
+		futures = append(futures, doubler.Ask(i))
+	}
+
+	result, err := goactors.Sequence(futures).GetResultTimeout(time.Second)
+	if err != nil {
+		t.Fatalf("Expected Sequence to succeed, received %v", err)
+	}
+	for i, value := range result.([]interface{}) {
+		if value != i*2 {
+			t.Errorf("Expected %v at %d, received %v", i*2, i, value)
+		}
+	}
+
+	silent := context.CreateActorFromFunc(func() goactors.Actor { return &silentActor{} }, "silent")
+	failed := goactors.Sequence([]goactors.Future{doubler.Ask(1), silent.AskWithTimeout("ping", 10*time.Millisecond)})
+	if _, err := failed.GetResultTimeout(time.Second); err != goactors.ErrAskTimeout {
+		t.Errorf("Expected %v, received %v", goactors.ErrAskTimeout, err)
+	}
+
+	first := goactors.FirstCompleted([]goactors.Future{silent.Ask("ping"), doubler.Ask(21)})
+	if result, err := first.GetResultTimeout(time.Second); result != 42 || err != nil {
+		t.Errorf("Expected %v, received %v, %v", 42, result, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestFirstCompletedWithoutFutures(t *testing.T) {
+	for _, futures := range [][]goactors.Future{nil, {}} {
+		if _, err := goactors.FirstCompleted(futures).GetResultTimeout(time.Second); err != goactors.ErrNoFutures {
+			t.Errorf("Expected %v, received %v", goactors.ErrNoFutures, err)
+		}
+	}
+}
+
+type failingReplyActor struct {
+	goactors.DefaultActor
+}
+
+func (a *failingReplyActor) Receive(context goactors.ActorContext, message interface{}) {
+	switch message {
+	case "fail":
+		context.SenderRef().Send(context.SelfRef(), goactors.Failure{Err: errTestFailure})
+	case "panic":
+		panic("boom")
+	default:
+		context.SenderRef().Send(context.SelfRef(), nil)
+	}
+}
+
+var errTestFailure = errors.New("test failure")
+
+func TestFutureResultCarriesFailures(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &failingReplyActor{} }, "failing")
+
+	if _, err := ref.Ask("fail").Result(); err != errTestFailure {
+		t.Errorf("Expected %v, received %v", errTestFailure, err)
+	}
+
+	if result, err := ref.Ask("nil").Result(); result != nil || err != nil {
+		t.Errorf("Expected a nil reply, received %v, %v", result, err)
+	}
+
+	// The actor is restarted by the default supervisor after failing the ask
+	if _, err := ref.Ask("panic").GetResultTimeout(time.Second); err == nil || err == goactors.ErrAskTimeout {
+		t.Errorf("Expected the panic to fail the future, received %v", err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestFutureFailsWhenTargetStops(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	release := make(chan struct{})
+
+	ref := createBlockedActor(context, release)
+	queued := ref.Ask("queued")
+	context.Kill(ref, 0)
+	close(release)
+
+	if _, err := queued.GetResultTimeout(time.Second); err != goactors.ErrActorStopped {
+		t.Errorf("Expected %v, received %v", goactors.ErrActorStopped, err)
+	}
+	if _, err := ref.Ask("ping").GetResultTimeout(time.Second); err != goactors.ErrActorStopped {
+		t.Errorf("Expected %v, received %v", goactors.ErrActorStopped, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestFutureHasManyReaders(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	echo := context.CreateActorFromFunc(func() goactors.Actor { return &echoActor{} }, "echo")
+	future := echo.Ask("ping")
+
+	results := make(chan interface{}, 20)
+	for i := 0; i < 10; i++ {
+		go func() { results <- future.GetResult() }()
+		future.OnComplete(func(value interface{}, err error) { results <- value })
+	}
+
+	for i := 0; i < 20; i++ {
+		select {
+		case result := <-results:
+			if result != "ping" {
+				t.Errorf("Expected %s, received %v", "ping", result)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the readers")
+		}
+	}
+
+	// Callbacks registered after completion run right away
+	called := false
+	future.OnComplete(func(value interface{}, err error) { called = value == "ping" })
+	if !called {
+		t.Errorf("Expected OnComplete to be called with the memoized result")
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestGenericAsk(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	doubler := context.CreateActorFromFunc(func() goactors.Actor { return &doublingActor{} }, "doubler")
+	if result, err := goactors.Ask[int, int](doubler, 21, time.Second); result != 42 || err != nil {
+		t.Errorf("Expected %v, received %v, %v", 42, result, err)
+	}
+
+	_, err := goactors.Ask[int, string](doubler, 21, time.Second)
+	if typeErr, ok := err.(*goactors.ReplyTypeError); !ok || typeErr.Reply != 42 {
+		t.Errorf("Expected a reply type error for %v, received %v", 42, err)
+	}
+
+	failing := context.CreateActorFromFunc(func() goactors.Actor { return &failingReplyActor{} }, "failing")
+	if _, err := goactors.Ask[string, int](failing, "fail", time.Second); err != errTestFailure {
+		t.Errorf("Expected %v, received %v", errTestFailure, err)
+	}
+	if result, err := goactors.Ask[string, error](failing, "nil", time.Second); result != nil || err != nil {
+		t.Errorf("Expected a nil reply, received %v, %v", result, err)
+	}
+
+	silent := context.CreateActorFromFunc(func() goactors.Actor { return &silentActor{} }, "silent")
+	if _, err := goactors.Ask[string, string](silent, "ping", 10*time.Millisecond); err != goactors.ErrAskTimeout {
+		t.Errorf("Expected %v, received %v", goactors.ErrAskTimeout, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestGenericAskDoubleSend(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	// Only the first of the two replies is the result
+	echoActor := context.CreateActorFromFunc(func() goactors.Actor { return &echoActorDoubleSender{} }, "echo")
+	if result, err := goactors.Ask[string, string](echoActor, "ping", time.Second); result != "ping" || err != nil {
+		t.Errorf("Expected %s, received %s, %v", "ping", result, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+type doubled struct {
+	value int
+}
+
+type pipingActor struct {
+	goactors.DefaultActor
+	doubler goactors.ActorRef
+	total   int
+}
+
+func (a *pipingActor) Receive(context goactors.ActorContext, message interface{}) {
+	switch message.(type) {
+	case int:
+		context.PipeToSelf(a.doubler.Ask(message), func(value interface{}, err error) interface{} {
+			return doubled{value: value.(int)}
+		})
+	case doubled:
+		// Still replies to whoever sent the int
+		a.total += message.(doubled).value
+		context.SenderRef().Send(context.SelfRef(), a.total)
+	}
+}
+
+func TestPipeToSelfKeepsSender(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	doubler := context.CreateActorFromFunc(func() goactors.Actor { return &doublingActor{} }, "doubler")
+	piping := context.CreateActorFromFunc(func() goactors.Actor { return &pipingActor{doubler: doubler} }, "piping")
+
+	if result, err := piping.Ask(2).GetResultTimeout(time.Second); result != 4 || err != nil {
+		t.Errorf("Expected %v, received %v, %v", 4, result, err)
+	}
+	if result, err := piping.Ask(3).GetResultTimeout(time.Second); result != 10 || err != nil {
+		t.Errorf("Expected %v, received %v, %v", 10, result, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type pipeTargetActor struct {
+	goactors.DefaultActor
+	received chan<- interface{}
+	senders  chan<- string
+}
+
+func (a *pipeTargetActor) Receive(context goactors.ActorContext, message interface{}) {
+	a.received <- message
+	if sender := context.SenderRef(); sender != nil {
+		a.senders <- sender.Path()
+	}
+}
+
+func TestFuturePipeTo(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	received := make(chan interface{}, 10)
+	senders := make(chan string, 10)
+
+	doubler := context.CreateActorFromFunc(func() goactors.Actor { return &doublingActor{} }, "doubler")
+	target := context.CreateActorFromFunc(func() goactors.Actor {
+		return &pipeTargetActor{received: received, senders: senders}
+	}, "target")
+
+	origin := context.CreateActorFromFunc(func() goactors.Actor { return &silentActor{} }, "origin")
+
+	// The target can reply to the origin rather than to the doubler
+	doubler.Ask(21).PipeTo(origin, target)
+	if message := <-received; message != 42 {
+		t.Errorf("Expected %v, received %v", 42, message)
+	}
+	if sender := <-senders; sender != "/test/origin" {
+		t.Errorf("Expected the sender to be %s, received %s", "/test/origin", sender)
+	}
+
+	promise := goactors.NewPromise()
+	promise.Future().PipeTo(nil, target)
+	promise.Fail(errTestFailure)
+	if message := <-received; message != (goactors.Failure{Err: errTestFailure}) {
+		t.Errorf("Expected %v, received %v", goactors.Failure{Err: errTestFailure}, message)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type pipeWaitingActor struct {
+	goactors.DefaultActor
+	future goactors.Future
+}
+
+func (a *pipeWaitingActor) Receive(context goactors.ActorContext, message interface{}) {
+	context.PipeToSelf(a.future, func(value interface{}, err error) interface{} {
+		return doubled{value: value.(int)}
+	})
+}
+
+func TestPipeToSelfOfStoppedActorIsDeadLettered(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+
+	promise := goactors.NewPromise()
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &pipeWaitingActor{future: promise.Future()} }, "waiting")
+	ref.Send(nil, "wait")
+	if err := context.StopGracefully(ref, time.Second); err != nil {
+		t.Fatalf("Expected actor to stop, received %v", err)
+	}
+
+	// The dead letter carries the result rather than the internal wrapper
+	promise.Complete(21)
+	expectDeadLetter(t, letters, "/test/waiting", 21)
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+type callbackActor struct {
+	goactors.DefaultActor
+}
+
+func (a *callbackActor) Receive(context goactors.ActorContext, message interface{}) {
+	// Calls back whoever is given in the message
+	message.(goactors.ActorRef).Send(context.SelfRef(), "done")
+}
+
+func TestPromiseCompletedByActor(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &callbackActor{} }, "callback")
+	promise := goactors.NewPromise()
+	ref.Send(nil, promise)
+
+	if result, err := promise.Future().GetResultTimeout(time.Second); result != "done" || err != nil {
+		t.Errorf("Expected %v, received %v, %v", "done", result, err)
+	}
+	if promise.Complete("again") {
+		t.Errorf("Expected a completed promise to stay completed")
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestPromiseCompletedFromGoroutine(t *testing.T) {
+	promise := goactors.NewPromise()
+	go promise.Complete(42)
+	if result, err := promise.Future().Result(); result != 42 || err != nil {
+		t.Errorf("Expected %v, received %v, %v", 42, result, err)
+	}
+
+	failed := goactors.NewPromise()
+	go failed.Fail(errTestFailure)
+	if _, err := failed.Future().Result(); err != errTestFailure {
+		t.Errorf("Expected %v, received %v", errTestFailure, err)
+	}
+}
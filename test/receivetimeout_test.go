@@ -0,0 +1,61 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+// sessionExpiryActor expires once it was idle for the timeout
+type sessionExpiryActor struct {
+	goactors.DefaultActor
+	events chan<- string
+}
+
+func (a *sessionExpiryActor) OnStart(context goactors.ActorContext) {
+	context.SetReceiveTimeout(50 * time.Millisecond)
+}
+
+func (a *sessionExpiryActor) Receive(context goactors.ActorContext, message interface{}) {
+	switch message.(type) {
+	case goactors.ReceiveTimeout:
+		a.events <- "expired"
+		context.SetReceiveTimeout(0)
+	default:
+		a.events <- "active"
+	}
+}
+
+func TestReceiveTimeoutResetsOnMessages(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 20)
+
+	start := time.Now()
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &sessionExpiryActor{events: events} }, "session")
+
+	// Keeps the session alive for longer than the timeout
+	for i := 0; i < 5; i++ {
+		ref.Send(nil, "keepalive")
+		expectEvent(t, events, "active")
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	expectEvent(t, events, "expired")
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the session to stay alive while active, it expired after %v", elapsed)
+	}
+
+	// Turned off after the first timeout
+	select {
+	case event := <-events:
+		t.Errorf("Expected no more events, received %q", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
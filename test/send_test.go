@@ -0,0 +1,104 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+type blockingActor struct {
+	goactors.DefaultActor
+	entered chan<- struct{}
+	release <-chan struct{}
+}
+
+func (a *blockingActor) Receive(ctxt goactors.ActorContext, message interface{}) {
+	a.entered <- struct{}{}
+	<-a.release
+}
+
+// createBlockedActor returns an actor stuck processing its first message until release is closed
+func createBlockedActor(ctxt goactors.ActorContext, release <-chan struct{}) goactors.ActorRef {
+	entered := make(chan struct{}, 100)
+	ref := ctxt.CreateActorFromFunc(func() goactors.Actor {
+		return &blockingActor{entered: entered, release: release}
+	}, "blocked")
+
+	ref.Send(nil, "block")
+	<-entered
+	return ref
+}
+
+// fillMailbox sends until the blocked actor's mailbox is full
+func fillMailbox(t *testing.T, ref goactors.ActorRef) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if err := ref.TrySend(nil, i); err == goactors.ErrMailboxFull {
+			return
+		} else if err != nil {
+			t.Fatalf("Expected TrySend to succeed or report a full mailbox, received %v", err)
+		}
+	}
+	t.Fatal("Expected the mailbox to fill up")
+}
+
+func TestTrySendReportsFullMailbox(t *testing.T) {
+	system := goactors.NewSystem("test")
+	ctxt := system.Context()
+	release := make(chan struct{})
+
+	ref := createBlockedActor(ctxt, release)
+	fillMailbox(t, ref)
+
+	close(release)
+	ctxt.Stop(ctxt.SelfRef())
+	system.Wait()
+}
+
+func TestTrySendReportsStoppedActor(t *testing.T) {
+	system := goactors.NewSystem("test")
+	ctxt := system.Context()
+
+	ref := ctxt.CreateActorFromFunc(func() goactors.Actor { return &goactors.DefaultActor{} }, "target")
+	if err := ctxt.StopGracefully(ref, time.Second); err != nil {
+		t.Fatalf("Expected actor to stop, received %v", err)
+	}
+
+	if err := ref.TrySend(nil, "ping"); err != goactors.ErrActorStopped {
+		t.Errorf("Expected %v, received %v", goactors.ErrActorStopped, err)
+	}
+	if err := ref.SendContext(context.Background(), nil, "ping"); err != goactors.ErrActorStopped {
+		t.Errorf("Expected %v, received %v", goactors.ErrActorStopped, err)
+	}
+
+	ctxt.Stop(ctxt.SelfRef())
+	system.Wait()
+}
+
+func TestSendContextRespectsCancellation(t *testing.T) {
+	system := goactors.NewSystem("test")
+	ctxt := system.Context()
+	release := make(chan struct{})
+
+	ref := createBlockedActor(ctxt, release)
+	fillMailbox(t, ref)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ref.SendContext(ctx, nil, "ping"); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, received %v", context.DeadlineExceeded, err)
+	}
+
+	// Once there is room again, the message is enqueued
+	close(release)
+	if err := ref.SendContext(context.Background(), nil, "ping"); err != nil {
+		t.Errorf("Expected SendContext to succeed, received %v", err)
+	}
+
+	ctxt.Stop(ctxt.SelfRef())
+	system.Wait()
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cgrunewald/goactors"
+)
+
+// initializingActor holds requests until it is ready
+type initializingActor struct {
+	goactors.DefaultActor
+	events chan<- string
+}
+
+func (a *initializingActor) OnStart(context goactors.ActorContext) {
+	context.SetStashCapacity(3)
+}
+
+func (a *initializingActor) Receive(context goactors.ActorContext, message interface{}) {
+	if message == "ready" {
+		context.Become(a.ready)
+		context.UnstashAll()
+		return
+	}
+
+	if err := context.Stash(); err != nil {
+		a.events <- fmt.Sprintf("%v: %v", message, err)
+	}
+}
+
+func (a *initializingActor) ready(context goactors.ActorContext, message interface{}) {
+	a.events <- fmt.Sprint(message)
+}
+
+func TestStashAndUnstashAll(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &initializingActor{events: events} }, "init")
+	for i := 1; i <= 4; i++ {
+		ref.Send(nil, i)
+	}
+	expectEvent(t, events, "4: "+goactors.ErrStashFull.Error())
+
+	ref.Send(nil, "ready")
+	ref.Send(nil, 5)
+	for _, expected := range []string{"1", "2", "3", "5"} {
+		expectEvent(t, events, expected)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestStashIsDeadLetteredOnStop(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+	events := make(chan string, 10)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &initializingActor{events: events} }, "init")
+	ref.Send(nil, "stashed")
+	context.Stop(ref)
+	expectDeadLetter(t, letters, "/test/init", "stashed")
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
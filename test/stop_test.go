@@ -0,0 +1,174 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+type stopReasonActor struct {
+	goactors.DefaultActor
+	events   chan<- string
+	children []string
+	path     string
+}
+
+func (a *stopReasonActor) OnStart(context goactors.ActorContext) {
+	a.path = context.Path()
+	for _, name := range a.children {
+		context.CreateActorFromFunc(func() goactors.Actor {
+			return &stopReasonActor{events: a.events}
+		}, name)
+	}
+}
+
+func (a *stopReasonActor) OnStopped(context goactors.ActorContext, reason goactors.StopReason) {
+	a.events <- fmt.Sprintf("%s %v", a.path, reason.Cause)
+}
+
+func (a *stopReasonActor) OnChildStopped(context goactors.ActorContext, child goactors.ActorRef, reason goactors.StopReason) {
+	a.events <- fmt.Sprintf("%s child %s %v", a.path, child.Path(), reason.Cause)
+}
+
+func (a *stopReasonActor) Receive(context goactors.ActorContext, message interface{}) {
+	switch message {
+	case "stop child":
+		context.Stop(context.GetChild("child"))
+	case "has child":
+		context.SenderRef().Send(context.SelfRef(), context.GetChild("child") != nil)
+	}
+}
+
+func TestStopReasonsForCascadeAndShutdown(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	for _, name := range []string{"a", "b"} {
+		context.CreateActorFromFunc(func() goactors.Actor {
+			return &stopReasonActor{events: events, children: []string{"child"}}
+		}, name)
+	}
+
+	context.Stop(context.FindActor("/test/a"))
+	expectEvent(t, events, "/test/a/child StopParentStopped")
+	expectEvent(t, events, "/test/a StopRequested")
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+	expectEvent(t, events, "/test/b/child StopSystemShutdown")
+	expectEvent(t, events, "/test/b StopSystemShutdown")
+}
+
+func TestParentIsNotifiedWhenChildStops(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	parent := context.CreateActorFromFunc(func() goactors.Actor {
+		return &stopReasonActor{events: events, children: []string{"child"}}
+	}, "parent")
+
+	parent.Send(nil, "stop child")
+	expectEvent(t, events, "/test/parent/child StopRequested")
+	expectEvent(t, events, "/test/parent child /test/parent/child StopRequested")
+
+	if hasChild := parent.Ask("has child").GetResult(); hasChild != false {
+		t.Errorf("Expected the stopped child to be removed from its parent")
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type slowActor struct {
+	goactors.DefaultActor
+	processed chan<- interface{}
+	delay     time.Duration
+}
+
+func (a *slowActor) Receive(context goactors.ActorContext, message interface{}) {
+	time.Sleep(a.delay)
+	a.processed <- message
+}
+
+func TestStopGracefullyProcessesQueuedMessages(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	processed := make(chan interface{}, 100)
+
+	// The proxy holds most of the messages in its own buffer
+	ref := context.CreateProxyActorFromFunc(func() goactors.Actor {
+		return &slowActor{processed: processed, delay: time.Millisecond}
+	}, "slow")
+	for i := 0; i < 50; i++ {
+		ref.Send(nil, i)
+	}
+
+	if err := context.StopGracefully(ref, 5*time.Second); err != nil {
+		t.Fatalf("Expected graceful stop to succeed, received %v", err)
+	}
+
+	if len(processed) != 50 {
+		t.Errorf("Expected %d processed messages, received %d", 50, len(processed))
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestKillDropsQueuedMessages(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	processed := make(chan interface{}, 100)
+	events := make(chan string, 10)
+
+	parent := context.CreateActorFromFunc(func() goactors.Actor {
+		return &stopReasonActor{events: events, children: []string{"child"}}
+	}, "parent")
+	ref := context.CreateActorFromFunc(func() goactors.Actor {
+		return &slowActor{processed: processed, delay: 10 * time.Millisecond}
+	}, "slow")
+	for i := 0; i < 10; i++ {
+		ref.Send(nil, i)
+	}
+
+	if err := context.Kill(ref, 5*time.Second); err != nil {
+		t.Fatalf("Expected kill to succeed, received %v", err)
+	}
+
+	if len(processed) >= 10 {
+		t.Errorf("Expected the kill to drop queued messages, all %d were processed", len(processed))
+	}
+
+	if err := context.Kill(parent, 5*time.Second); err != nil {
+		t.Fatalf("Expected kill to succeed, received %v", err)
+	}
+	expectEvent(t, events, "/test/parent/child StopParentStopped")
+	expectEvent(t, events, "/test/parent StopKilled")
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestStopGracefullyTimesOut(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	processed := make(chan interface{}, 100)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor {
+		return &slowActor{processed: processed, delay: 200 * time.Millisecond}
+	}, "slow")
+	ref.Send(nil, "busy")
+
+	if err := context.StopGracefully(ref, 10*time.Millisecond); err != goactors.ErrStopTimeout {
+		t.Errorf("Expected %v, received %v", goactors.ErrStopTimeout, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
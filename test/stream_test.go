@@ -0,0 +1,129 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+type pagingActor struct {
+	goactors.DefaultActor
+}
+
+func (a *pagingActor) Receive(context goactors.ActorContext, message interface{}) {
+	pages := message.(int)
+	for i := 0; i < pages; i++ {
+		context.SenderRef().Send(context.SelfRef(), i)
+	}
+
+	if pages < 0 {
+		context.SenderRef().Send(context.SelfRef(), goactors.Failure{Err: errTestFailure})
+		return
+	}
+	context.SenderRef().Send(context.SelfRef(), goactors.EndOfStream{})
+}
+
+func collectReplies(t *testing.T, stream goactors.Stream) []interface{} {
+	t.Helper()
+	replies := []interface{}{}
+	for {
+		select {
+		case reply, ok := <-stream.Replies():
+			if !ok {
+				return replies
+			}
+			replies = append(replies, reply)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the stream to end")
+		}
+	}
+}
+
+func TestAskStreamDeliversAllReplies(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	pager := context.CreateActorFromFunc(func() goactors.Actor { return &pagingActor{} }, "pager")
+
+	stream := pager.AskStream(20)
+	replies := collectReplies(t, stream)
+	if len(replies) != 20 {
+		t.Fatalf("Expected %d replies, received %d", 20, len(replies))
+	}
+	for i, reply := range replies {
+		if reply != i {
+			t.Errorf("Expected reply %d, received %v", i, reply)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Errorf("Expected the stream to end cleanly, received %v", err)
+	}
+
+	failed := pager.AskStream(-1)
+	if replies := collectReplies(t, failed); len(replies) != 0 || failed.Err() != errTestFailure {
+		t.Errorf("Expected the stream to fail with %v, received %v after %d replies", errTestFailure, failed.Err(), len(replies))
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type cancellableActor struct {
+	goactors.DefaultActor
+	events chan<- string
+}
+
+func (a *cancellableActor) Receive(context goactors.ActorContext, message interface{}) {
+	if _, ok := message.(goactors.StreamCancelled); ok {
+		a.events <- "cancelled by " + context.SenderRef().Path()
+	}
+}
+
+func TestAskStreamCancel(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+
+	events := make(chan string, 10)
+	producer := context.CreateActorFromFunc(func() goactors.Actor { return &cancellableActor{events: events} }, "producer")
+	stream := producer.AskStream("query")
+	stream.Cancel()
+	collectReplies(t, stream)
+
+	// The actor learns that nobody reads its replies anymore
+	expectEvent(t, events, "cancelled by stream")
+
+	// The reply after cancelling is dead lettered
+	stream.(goactors.ActorRef).Send(nil, "late")
+	expectDeadLetter(t, letters, "stream", "late")
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestAskStreamEndsWhenBufferOverflows(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+
+	pager := context.CreateActorFromFunc(func() goactors.Actor { return &pagingActor{} }, "pager")
+
+	// Nothing is read until the reply past the buffer was dead lettered
+	stream := pager.AskStream(goactors.StreamBufferSize + 1)
+	expectDeadLetter(t, letters, "stream", goactors.StreamBufferSize)
+
+	if replies := collectReplies(t, stream); len(replies) != goactors.StreamBufferSize {
+		t.Errorf("Expected %d replies, received %d", goactors.StreamBufferSize, len(replies))
+	}
+	if err := stream.Err(); err != goactors.ErrMailboxFull {
+		t.Errorf("Expected %v, received %v", goactors.ErrMailboxFull, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
@@ -0,0 +1,439 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+type flakyActor struct {
+	goactors.DefaultActor
+	events   chan<- string
+	received int
+}
+
+func (a *flakyActor) OnStart(context goactors.ActorContext) {
+	a.events <- "start " + context.Path()
+}
+
+func (a *flakyActor) OnStop() {
+	a.events <- "stop"
+}
+
+func (a *flakyActor) Receive(context goactors.ActorContext, message interface{}) {
+	a.received++
+	switch message {
+	case "panic":
+		panic("bad message")
+	case "count":
+		context.SenderRef().Send(context.SelfRef(), a.received)
+	}
+}
+
+type supervisingActor struct {
+	goactors.DefaultActor
+	strategy goactors.SupervisorStrategy
+	events   chan<- string
+	child    goactors.ActorRef
+}
+
+func (a *supervisingActor) OnStart(context goactors.ActorContext) {
+	a.events <- "start " + context.Path()
+	context.SetSupervisorStrategy(a.strategy)
+	a.child = context.CreateActorFromFunc(func() goactors.Actor {
+		return &flakyActor{events: a.events}
+	}, "child")
+}
+
+func (a *supervisingActor) Receive(context goactors.ActorContext, message interface{}) {
+	a.child.Send(context.SenderRef(), message)
+}
+
+func expectEvent(t *testing.T, events <-chan string, expected string) {
+	t.Helper()
+	select {
+	case event := <-events:
+		if event != expected {
+			t.Errorf("Expected event %q, received %q", expected, event)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for event %q", expected)
+	}
+}
+
+func TestSupervisorRestartsFailedChild(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor {
+		return &flakyActor{events: events}
+	}, "flaky")
+	expectEvent(t, events, "start /test/flaky")
+
+	ref.Send(nil, "panic")
+	expectEvent(t, events, "stop")
+	expectEvent(t, events, "start /test/flaky")
+
+	// The restarted instance starts with fresh state
+	if count := ref.Ask("count").GetResult(); count != 1 {
+		t.Errorf("Expected count %d, received %v", 1, count)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestSupervisorResumesFailedChild(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	context.SetSupervisorStrategy(goactors.Decider(func(reason interface{}) goactors.Directive {
+		return goactors.Resume
+	}))
+	events := make(chan string, 10)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor {
+		return &flakyActor{events: events}
+	}, "flaky")
+	expectEvent(t, events, "start /test/flaky")
+
+	ref.Send(nil, "ping")
+	ref.Send(nil, "panic")
+
+	// The resumed instance keeps its state
+	if count := ref.Ask("count").GetResult(); count != 3 {
+		t.Errorf("Expected count %d, received %v", 3, count)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestSupervisorStopsFailedChild(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	context.CreateActorFromFunc(func() goactors.Actor {
+		return &supervisingActor{
+			events: events,
+			strategy: goactors.Decider(func(reason interface{}) goactors.Directive {
+				return goactors.Stop
+			}),
+		}
+	}, "parent")
+	expectEvent(t, events, "start /test/parent")
+	expectEvent(t, events, "start /test/parent/child")
+
+	parent := context.FindActor("/test/parent")
+	parent.Send(nil, "panic")
+	expectEvent(t, events, "stop")
+
+	if ref := context.FindActor("/test/parent/child"); ref != nil {
+		t.Errorf("Expected stopped child to be unregistered, found %v", ref.Path())
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestSupervisorEscalatesFailure(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	context.CreateActorFromFunc(func() goactors.Actor {
+		return &supervisingActor{
+			events: events,
+			strategy: goactors.Decider(func(reason interface{}) goactors.Directive {
+				return goactors.Escalate
+			}),
+		}
+	}, "parent")
+	expectEvent(t, events, "start /test/parent")
+	expectEvent(t, events, "start /test/parent/child")
+
+	// The root restarts the parent, which stops and recreates the child
+	context.FindActor("/test/parent").Send(nil, "panic")
+	expectEvent(t, events, "stop")
+	expectEvent(t, events, "start /test/parent")
+	expectEvent(t, events, "start /test/parent/child")
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type failingStartActor struct {
+	goactors.DefaultActor
+	starts chan<- int
+}
+
+var failingStartAttempts int
+
+func (a *failingStartActor) OnStart(context goactors.ActorContext) {
+	failingStartAttempts++
+	a.starts <- failingStartAttempts
+	if failingStartAttempts < 3 {
+		panic("could not start")
+	}
+}
+
+func TestSupervisorRestartsActorThatFailedToStart(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	starts := make(chan int, 10)
+	failingStartAttempts = 0
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor {
+		return &failingStartActor{starts: starts}
+	}, "failing")
+	if ref == nil {
+		t.Fatal("Expected a ref to the actor even though OnStart panicked")
+	}
+
+	for i := 1; i <= 3; i++ {
+		select {
+		case attempt := <-starts:
+			if attempt != i {
+				t.Errorf("Expected start attempt %d, received %d", i, attempt)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for start attempt %d", i)
+		}
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type neverStartingActor struct {
+	goactors.DefaultActor
+	starts chan<- string
+}
+
+func (a *neverStartingActor) OnStart(context goactors.ActorContext) {
+	a.starts <- "start"
+	panic("never starts")
+}
+
+func TestDefaultStrategyStopsActorThatNeverStarts(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	starts := make(chan string, 2*goactors.DefaultMaxRestarts)
+
+	context.CreateActorFromFunc(func() goactors.Actor {
+		return &neverStartingActor{starts: starts}
+	}, "failing")
+
+	// The first start and every restart up to the limit
+	for i := 0; i <= goactors.DefaultMaxRestarts; i++ {
+		expectEvent(t, starts, "start")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for context.FindActor("/test/failing") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the actor to be stopped once it exceeded the restart limit")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	expectNoEvent(t, starts, 20*time.Millisecond)
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestOneForOneStrategyEscalatesAfterMaxRestarts(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	context.CreateActorFromFunc(func() goactors.Actor {
+		return &supervisingActor{
+			events:   events,
+			strategy: goactors.NewOneForOneStrategy(2, time.Minute, nil),
+		}
+	}, "parent")
+	expectEvent(t, events, "start /test/parent")
+	expectEvent(t, events, "start /test/parent/child")
+
+	parent := context.FindActor("/test/parent")
+	for i := 0; i < 2; i++ {
+		parent.Send(nil, "panic")
+		expectEvent(t, events, "stop")
+		expectEvent(t, events, "start /test/parent/child")
+	}
+
+	// The third restart exceeds the limit, so the root restarts the parent instead
+	parent.Send(nil, "panic")
+	expectEvent(t, events, "stop")
+	expectEvent(t, events, "start /test/parent")
+	expectEvent(t, events, "start /test/parent/child")
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type siblingsActor struct {
+	goactors.DefaultActor
+	events chan<- string
+}
+
+func (a *siblingsActor) OnStart(context goactors.ActorContext) {
+	context.SetSupervisorStrategy(goactors.NewAllForOneStrategy(-1, 0, nil))
+	for _, name := range []string{"reader", "encoder"} {
+		context.CreateActorFromFunc(func() goactors.Actor {
+			return &flakyActor{events: a.events}
+		}, name)
+	}
+}
+
+func (a *siblingsActor) Receive(context goactors.ActorContext, message interface{}) {
+	context.GetChild("reader").Send(context.SenderRef(), message)
+}
+
+func TestAllForOneStrategyRestartsSiblings(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	context.CreateActorFromFunc(func() goactors.Actor {
+		return &siblingsActor{events: events}
+	}, "pipeline")
+	expectEvent(t, events, "start /test/pipeline/reader")
+	expectEvent(t, events, "start /test/pipeline/encoder")
+
+	context.FindActor("/test/pipeline").Send(nil, "panic")
+
+	// Both siblings restart concurrently, so only the set of events is deterministic
+	received := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		select {
+		case event := <-events:
+			received[event]++
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for restart events, received %v", received)
+		}
+	}
+
+	expected := map[string]int{
+		"stop":                         2,
+		"start /test/pipeline/reader":  1,
+		"start /test/pipeline/encoder": 1,
+	}
+	for event, count := range expected {
+		if received[event] != count {
+			t.Errorf("Expected %d %q events, received %d", count, event, received[event])
+		}
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+type restartHooksActor struct {
+	flakyActor
+}
+
+func (a *restartHooksActor) PreRestart(context goactors.ActorContext, reason interface{}, lastMessage interface{}) {
+	a.events <- fmt.Sprintf("preRestart %v %v", reason, lastMessage)
+}
+
+func (a *restartHooksActor) PostRestart(context goactors.ActorContext, reason interface{}) {
+	a.events <- fmt.Sprintf("postRestart %v", reason)
+}
+
+func TestRestartHooksReplaceStartAndStop(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor {
+		return &restartHooksActor{flakyActor{events: events}}
+	}, "hooks")
+	expectEvent(t, events, "start /test/hooks")
+
+	ref.Send(nil, "panic")
+	expectEvent(t, events, "preRestart bad message panic")
+	expectEvent(t, events, "postRestart bad message")
+
+	// Stopping still goes through OnStop
+	context.Stop(context.SelfRef())
+	system.Wait()
+	expectEvent(t, events, "stop")
+}
+
+type failingPreRestartActor struct {
+	flakyActor
+	failures *int
+}
+
+func (a *failingPreRestartActor) PreRestart(context goactors.ActorContext, reason interface{}, lastMessage interface{}) {
+	if *a.failures > 0 {
+		*a.failures--
+		panic("could not clean up")
+	}
+	a.events <- "preRestart"
+}
+
+type decidingActor struct {
+	goactors.DefaultActor
+	events  chan<- string
+	reasons chan<- interface{}
+	child   goactors.ActorRef
+}
+
+func (a *decidingActor) OnStart(context goactors.ActorContext) {
+	context.SetSupervisorStrategy(goactors.Decider(func(reason interface{}) goactors.Directive {
+		a.reasons <- reason
+		return goactors.Restart
+	}))
+
+	failures := 1
+	a.child = context.CreateActorFromFunc(func() goactors.Actor {
+		return &failingPreRestartActor{flakyActor: flakyActor{events: a.events}, failures: &failures}
+	}, "child")
+}
+
+func (a *decidingActor) Receive(context goactors.ActorContext, message interface{}) {
+	a.child.Send(context.SenderRef(), message)
+}
+
+func expectReason(t *testing.T, reasons <-chan interface{}, expected interface{}) {
+	t.Helper()
+	select {
+	case reason := <-reasons:
+		if reason != expected {
+			t.Errorf("Expected the supervisor to see %v, received %v", expected, reason)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for the supervisor to see %v", expected)
+	}
+}
+
+func TestPanickingPreRestartGoesToSupervisor(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 10)
+	reasons := make(chan interface{}, 10)
+
+	parent := context.CreateActorFromFunc(func() goactors.Actor {
+		return &decidingActor{events: events, reasons: reasons}
+	}, "parent")
+	expectEvent(t, events, "start /test/parent/child")
+
+	parent.Send(nil, "panic")
+	expectReason(t, reasons, "bad message")
+
+	// The failed clean up is a failure of its own, so the supervisor decides again
+	expectReason(t, reasons, "could not clean up")
+	expectEvent(t, events, "preRestart")
+	expectEvent(t, events, "start /test/parent/child")
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+type timerActor struct {
+	goactors.DefaultActor
+	events chan<- string
+}
+
+func (a *timerActor) Receive(context goactors.ActorContext, message interface{}) {
+	switch message {
+	case "start single":
+		context.Timers().StartSingle("single", "single fired", 20*time.Millisecond)
+	case "start periodic":
+		context.Timers().StartPeriodic("periodic", "tick", 10*time.Millisecond)
+	case "cancel periodic":
+		context.Timers().Cancel("periodic")
+		a.events <- "cancelled"
+	case "panic":
+		panic("boom")
+	case "single fired", "tick":
+		a.events <- fmt.Sprint(message)
+	}
+}
+
+func expectNoEvent(t *testing.T, events <-chan string, wait time.Duration) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Errorf("Expected no more events, received %q", event)
+	case <-time.After(wait):
+	}
+}
+
+func TestTimersSingleAndPeriodic(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 100)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &timerActor{events: events} }, "timers")
+	ref.Send(nil, "start single")
+	expectEvent(t, events, "single fired")
+	expectNoEvent(t, events, 50*time.Millisecond)
+
+	ref.Send(nil, "start periodic")
+	for i := 0; i < 3; i++ {
+		expectEvent(t, events, "tick")
+	}
+
+	// No tick arrives after the cancel, even one that was already queued
+	ref.Send(nil, "cancel periodic")
+	for event := range events {
+		if event == "cancelled" {
+			break
+		}
+	}
+	expectNoEvent(t, events, 50*time.Millisecond)
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestTimersAreCancelledOnRestartAndStop(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+	events := make(chan string, 100)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &timerActor{events: events} }, "timers")
+	ref.Send(nil, "start single")
+	ref.Send(nil, "panic")
+	expectNoEvent(t, events, 50*time.Millisecond)
+
+	ref.Send(nil, "start periodic")
+	expectEvent(t, events, "tick")
+	if err := context.StopGracefully(ref, time.Second); err != nil {
+		t.Fatalf("Expected actor to stop, received %v", err)
+	}
+
+	// A tick that was already on its way goes to the dead letters as the tick itself
+	settled := time.After(30 * time.Millisecond)
+drain:
+	for {
+		select {
+		case letter := <-letters:
+			if letter.Message != "tick" {
+				t.Errorf("Expected a tick dead letter, received %v", letter.Message)
+			}
+		case <-settled:
+			break drain
+		}
+	}
+
+	select {
+	case letter := <-letters:
+		t.Errorf("Expected no ticks after the stop, received dead letter %v", letter.Message)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestSchedulerScheduleOnceAndRepeatedly(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	events := make(chan string, 100)
+
+	ref := context.CreateActorFromFunc(func() goactors.Actor { return &timerActor{events: events} }, "timers")
+	system.Scheduler().ScheduleOnce(10*time.Millisecond, ref, "single fired")
+	expectEvent(t, events, "single fired")
+
+	task := system.Scheduler().ScheduleRepeatedly(0, 10*time.Millisecond, ref, "tick")
+	for i := 0; i < 3; i++ {
+		expectEvent(t, events, "tick")
+	}
+	if !task.Cancel() {
+		t.Errorf("Expected the task to still be scheduled")
+	}
+	if task.Cancel() {
+		t.Errorf("Expected a cancelled task to stay cancelled")
+	}
+
+	// A tick may have been sent just before the cancel
+	time.Sleep(20 * time.Millisecond)
+	for len(events) > 0 {
+		<-events
+	}
+	expectNoEvent(t, events, 50*time.Millisecond)
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+func TestSchedulerRejectsNonPositiveInterval(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected interval %v to be rejected", interval)
+				}
+			}()
+			system.Scheduler().ScheduleRepeatedly(time.Millisecond, interval, context.SelfRef(), "tick")
+		}()
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
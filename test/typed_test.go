@@ -0,0 +1,145 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgrunewald/goactors"
+)
+
+func expectUnhandled(t *testing.T, letters <-chan goactors.DeadLetter, recipient string, message interface{}) {
+	t.Helper()
+	select {
+	case letter := <-letters:
+		if letter.Recipient.Path() != recipient || letter.Message != message || !letter.Unhandled {
+			t.Errorf("Expected %v to %s to be unhandled, received %+v", message, recipient, letter)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for unhandled message %v", message)
+	}
+}
+
+type counterMessage interface {
+	isCounterMessage()
+}
+
+type increment struct {
+	by int
+}
+
+type getCount struct{}
+
+func (increment) isCounterMessage() {}
+func (getCount) isCounterMessage()  {}
+
+type counterActor struct {
+	goactors.DefaultActor
+	count int
+}
+
+func (a *counterActor) Receive(context goactors.ActorContext, message counterMessage) {
+	switch message.(type) {
+	case increment:
+		a.count += message.(increment).by
+	case getCount:
+		context.SenderRef().Send(context.SelfRef(), a.count)
+	}
+}
+
+func TestSpawnTypedActor(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+
+	behavior := func() goactors.TypedActor[counterMessage] {
+		return &counterActor{}
+	}
+	counter, err := goactors.Spawn[counterMessage](context, behavior, "counter")
+	if err != nil {
+		t.Fatalf("Expected the counter to be spawned, received %v", err)
+	}
+	if _, err := goactors.Spawn[counterMessage](context, behavior, "counter"); err != goactors.ErrActorExists {
+		t.Errorf("Expected %v, received %v", goactors.ErrActorExists, err)
+	}
+
+	counter.Send(nil, increment{by: 2})
+	counter.Send(nil, increment{by: 3})
+	if result, err := counter.Ask(getCount{}).GetResultTimeout(time.Second); result != 5 || err != nil {
+		t.Errorf("Expected %v, received %v, %v", 5, result, err)
+	}
+
+	// Refs found by path are typed again, and messages of another type are unhandled
+	found := goactors.NewTypedRef[counterMessage](context.FindActor("/test/counter"))
+	found.Send(nil, increment{by: 1})
+	found.Untyped().Send(nil, "not a counter message")
+	expectUnhandled(t, letters, "/test/counter", "not a counter message")
+
+	// Asking with another type fails fast rather than at the timeout
+	if _, err := found.Untyped().Ask("not a counter message").GetResultTimeout(time.Second); err != goactors.ErrUnhandledMessage {
+		t.Errorf("Expected %v, received %v", goactors.ErrUnhandledMessage, err)
+	}
+	expectUnhandled(t, letters, "/test/counter", "not a counter message")
+
+	if result, err := found.Ask(getCount{}).GetResultTimeout(time.Second); result != 6 || err != nil {
+		t.Errorf("Expected %v, received %v, %v", 6, result, err)
+	}
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
+
+// idleCounterActor asks for a receive timeout, which is not a counter message
+type idleCounterActor struct {
+	counterActor
+	events chan string
+}
+
+func (a *idleCounterActor) OnStart(context goactors.ActorContext) {
+	context.SetReceiveTimeout(20 * time.Millisecond)
+}
+
+// signalCounterActor also handles the receive timeout
+type signalCounterActor struct {
+	idleCounterActor
+}
+
+func (a *signalCounterActor) OnSignal(context goactors.ActorContext, signal interface{}) {
+	switch signal.(type) {
+	case goactors.ReceiveTimeout:
+		a.events <- "receive timeout"
+		context.SetReceiveTimeout(0)
+	}
+}
+
+func TestTypedActorSignals(t *testing.T) {
+	system := goactors.NewSystem("test")
+	context := system.Context()
+	letters, unsubscribe := subscribeDeadLetters(system)
+	defer unsubscribe()
+	events := make(chan string, 10)
+
+	// A typed actor without a SignalHandler does not handle the notification
+	_, err := goactors.Spawn[counterMessage](context, func() goactors.TypedActor[counterMessage] {
+		return &idleCounterActor{events: events}
+	}, "idle")
+	if err != nil {
+		t.Fatalf("Expected the counter to be spawned, received %v", err)
+	}
+	expectUnhandled(t, letters, "/test/idle", goactors.ReceiveTimeout{})
+	context.Stop(context.FindActor("/test/idle"))
+
+	_, err = goactors.Spawn[counterMessage](context, func() goactors.TypedActor[counterMessage] {
+		return &signalCounterActor{idleCounterActor{events: events}}
+	}, "signal")
+	if err != nil {
+		t.Fatalf("Expected the counter to be spawned, received %v", err)
+	}
+	expectEvent(t, events, "receive timeout")
+	expectNoEvent(t, events, 60*time.Millisecond)
+
+	context.Stop(context.SelfRef())
+	system.Wait()
+}
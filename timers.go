@@ -0,0 +1,90 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"time"
+)
+
+// Timers are an actor's named timers, see ActorContext.Timers. Starting a timer with the
+// key of an active one replaces it. Timer messages arrive without a sender, and never
+// from a timer that was cancelled or replaced, or that was started before a restart.
+type Timers struct {
+	scheduler  *Scheduler
+	self       ActorRef
+	timers     map[interface{}]actorTimer
+	generation int
+}
+
+type actorTimer struct {
+	task       *ScheduledTask
+	generation int
+	periodic   bool
+}
+
+func newTimers(scheduler *Scheduler) *Timers {
+	return &Timers{
+		scheduler: scheduler,
+		timers:    make(map[interface{}]actorTimer),
+	}
+}
+
+// StartSingle sends the message to the actor once the delay has passed
+func (timers *Timers) StartSingle(key interface{}, message interface{}, delay time.Duration) {
+	timers.Cancel(key)
+	generation := timers.nextGeneration()
+	task := timers.scheduler.ScheduleOnce(delay, timers.self, timerMessage{key: key, generation: generation, message: message})
+	timers.timers[key] = actorTimer{task: task, generation: generation}
+}
+
+// StartPeriodic sends the message to the actor every interval until it is cancelled. It
+// panics if the interval is not positive.
+func (timers *Timers) StartPeriodic(key interface{}, message interface{}, interval time.Duration) {
+	if interval <= 0 {
+		panic("goactors: non-positive interval for StartPeriodic")
+	}
+
+	timers.Cancel(key)
+	generation := timers.nextGeneration()
+	task := timers.scheduler.ScheduleRepeatedly(interval, interval, timers.self, timerMessage{key: key, generation: generation, message: message})
+	timers.timers[key] = actorTimer{task: task, generation: generation, periodic: true}
+}
+
+// Cancel stops the timer. A message it already sent is dropped as well.
+func (timers *Timers) Cancel(key interface{}) {
+	if timer, ok := timers.timers[key]; ok {
+		timer.task.Cancel()
+		delete(timers.timers, key)
+	}
+}
+
+// CancelAll stops every timer of the actor
+func (timers *Timers) CancelAll() {
+	for key := range timers.timers {
+		timers.Cancel(key)
+	}
+}
+
+// IsActive reports whether the timer will still send a message
+func (timers *Timers) IsActive(key interface{}) bool {
+	_, ok := timers.timers[key]
+	return ok
+}
+
+func (timers *Timers) nextGeneration() int {
+	timers.generation++
+	return timers.generation
+}
+
+// accept unwraps a timer message, unless it is from a timer that is no longer active
+func (timers *Timers) accept(msg timerMessage) (interface{}, bool) {
+	timer, ok := timers.timers[msg.key]
+	if !ok || timer.generation != msg.generation {
+		return nil, false
+	}
+
+	if !timer.periodic {
+		delete(timers.timers, msg.key)
+	}
+	return msg.message, true
+}
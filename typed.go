@@ -0,0 +1,152 @@
+// Copyright 2019 Calvin Grunewald. All rights reserved.
+
+package goactors
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrActorExists is returned by Spawn when the parent already has a child with the name
+var ErrActorExists = errors.New("goactors: an actor with this name already exists")
+
+// TypedActor is an actor that only accepts messages of type M. Like Actor, it may also
+// implement the optional lifecycle interfaces such as StopObserver or PreRestarter, and
+// SignalHandler to get the notifications of the actor system.
+type TypedActor[M any] interface {
+	OnStart(context ActorContext)
+	OnStop()
+	Receive(context ActorContext, message M)
+}
+
+// SignalHandler receives the notifications the actor system sends to a typed actor that
+// are not of its message type: Terminated, ReceiveTimeout, StateTimeout and
+// StreamCancelled. Without it they are reported as unhandled.
+type SignalHandler interface {
+	OnSignal(context ActorContext, signal interface{})
+}
+
+// TypedBehavior creates the typed actor, again on every restart
+type TypedBehavior[M any] func() TypedActor[M]
+
+// TypedRef is an ActorRef that only sends messages of type M
+type TypedRef[M any] struct {
+	ref ActorRef
+}
+
+// NewTypedRef wraps an untyped ref, for example one returned by FindActor. Messages of
+// another type that reach a typed actor through an untyped ref are published to the
+// dead letters as unhandled, and asks with them fail with ErrUnhandledMessage.
+func NewTypedRef[M any](ref ActorRef) TypedRef[M] {
+	return TypedRef[M]{ref: ref}
+}
+
+// Spawn creates a child of the context running the typed actor. It fails with
+// ErrActorExists if the name is taken.
+func Spawn[M any](context ActorContext, behavior TypedBehavior[M], name string) (TypedRef[M], error) {
+	ref := context.CreateActorFromFunc(func() Actor {
+		return &typedActor[M]{behavior: behavior()}
+	}, name)
+	if ref == nil {
+		return TypedRef[M]{}, ErrActorExists
+	}
+	return NewTypedRef[M](ref), nil
+}
+
+// Untyped returns the underlying ActorRef
+func (ref TypedRef[M]) Untyped() ActorRef {
+	return ref.ref
+}
+
+func (ref TypedRef[M]) Path() string {
+	return ref.ref.Path()
+}
+
+func (ref TypedRef[M]) Send(sender ActorRef, message M) {
+	ref.ref.Send(sender, message)
+}
+
+func (ref TypedRef[M]) TrySend(sender ActorRef, message M) error {
+	return ref.ref.TrySend(sender, message)
+}
+
+func (ref TypedRef[M]) SendContext(ctx context.Context, sender ActorRef, message M) error {
+	return ref.ref.SendContext(ctx, sender, message)
+}
+
+func (ref TypedRef[M]) Ask(message M) Future {
+	return ref.ref.Ask(message)
+}
+
+func (ref TypedRef[M]) AskWithTimeout(message M, timeout time.Duration) Future {
+	return ref.ref.AskWithTimeout(message, timeout)
+}
+
+// typedActor adapts a TypedActor to Actor, passing on the optional interfaces
+type typedActor[M any] struct {
+	behavior TypedActor[M]
+}
+
+func (actor *typedActor[M]) OnStart(context ActorContext) {
+	actor.behavior.OnStart(context)
+}
+
+func (actor *typedActor[M]) OnStop() {
+	actor.behavior.OnStop()
+}
+
+func (actor *typedActor[M]) Receive(context ActorContext, message interface{}) {
+	typed, ok := message.(M)
+	if ok {
+		actor.behavior.Receive(context, typed)
+		return
+	}
+
+	if handler, ok := actor.behavior.(SignalHandler); ok && isSignal(message) {
+		handler.OnSignal(context, message)
+		return
+	}
+
+	// An asker fails right away instead of waiting for its timeout
+	context.DeadLetters().unhandled(DeadLetter{Sender: context.SenderRef(), Recipient: context.SelfRef(), Message: message})
+}
+
+// isSignal reports whether the message is a notification of the actor system
+func isSignal(message interface{}) bool {
+	switch message.(type) {
+	case Terminated, ReceiveTimeout, StateTimeout, StreamCancelled:
+		return true
+	}
+	return false
+}
+
+func (actor *typedActor[M]) OnStopped(context ActorContext, reason StopReason) {
+	if observer, ok := actor.behavior.(StopObserver); ok {
+		observer.OnStopped(context, reason)
+	} else {
+		actor.behavior.OnStop()
+	}
+}
+
+func (actor *typedActor[M]) OnChildStopped(context ActorContext, child ActorRef, reason StopReason) {
+	if observer, ok := actor.behavior.(ChildStopObserver); ok {
+		observer.OnChildStopped(context, child, reason)
+	}
+}
+
+func (actor *typedActor[M]) PreRestart(context ActorContext, reason interface{}, lastMessage interface{}) {
+	if restarter, ok := actor.behavior.(PreRestarter); ok {
+		restarter.PreRestart(context, reason, lastMessage)
+	} else {
+		actor.behavior.OnStop()
+	}
+}
+
+func (actor *typedActor[M]) PostRestart(context ActorContext, reason interface{}) {
+	if restarter, ok := actor.behavior.(PostRestarter); ok {
+		restarter.PostRestart(context, reason)
+	} else {
+		actor.behavior.OnStart(context)
+	}
+}